@@ -0,0 +1,97 @@
+package tree
+
+// SetAugment installs user-defined augmentation of tree and
+// recalculates it for all nodes.
+//
+// Augmentation maintains aggregate values of subtrees (sums, maxima,
+// etc). update is called whenever subtree rooted at element ix is
+// changed, and should recalculate its aggregate from value of element
+// ix and aggregates of subtrees rooted at left and right (-1 if there is
+// no such subtree). Aggregates should be stored with elements in data,
+// so that data.Swap moves them together with elements.
+//
+//     index.SetAugment(func(ix, l, r int) {
+//         rows[ix].Sum = rows[ix].Price
+//         if l >= 0 { rows[ix].Sum += rows[l].Sum }
+//         if r >= 0 { rows[ix].Sum += rows[r].Sum }
+//     })
+//
+// update may be called with element of data appended but not yet
+// inserted, so closure should capture data by reference.
+// SetAugment(nil) removes augmentation.
+func (t *Tree) SetAugment(update func(ix, left, right int)) {
+	t.aug = update
+	if len(t.nodes) > 0 {
+		t.augment(t.root)
+	}
+}
+
+// Aggregate combines values of elements of t from first element for
+// which from is true (as in Search) up to last element for which to is
+// true (as in SearchLast), and reports if range is not empty.
+// nil from or to means that range is not bounded from that side.
+//
+// value returns either aggregate of whole subtree rooted at ix (subtree
+// is true) or value of single element ix (subtree is false); results
+// are combined in order of elements, so combine needn't be commutative.
+// value is called O(log n) times.
+//
+//     sum, _ := tree.Aggregate(index,
+//         func(i int) bool { return rows[i].Price >= a },
+//         func(i int) bool { return rows[i].Price <= b },
+//         func(ix int, subtree bool) int {
+//             if subtree {
+//                 return rows[ix].Sum
+//             }
+//             return rows[ix].Price
+//         },
+//         func(a, b int) int { return a + b })
+//
+// Aggregate is a function, not a method, since methods can't have
+// type parameters.
+func Aggregate[T any](t *Tree, from, to func(i int) bool, value func(ix int, subtree bool) T, combine func(a, b T) T) (T, bool) {
+	var res T
+	ok := false
+	if len(t.nodes) == 0 {
+		return res, false
+	}
+	t.aggregate(t.root, from, to, func(ix int, subtree bool) {
+		if v := value(ix, subtree); ok {
+			res = combine(res, v)
+		} else {
+			res, ok = v, true
+		}
+	})
+	return res, ok
+}
+
+func (t *Tree) aggregate(ix int, from, to func(i int) bool, fold func(ix int, subtree bool)) {
+	for ix != null {
+		if from == nil && to == nil {
+			fold(ix, true)
+			return
+		}
+		n := &t.nodes[ix]
+		if from != nil && !from(ix) {
+			ix = int(n._right)
+		} else if to != nil && !to(ix) {
+			ix = int(n._left)
+		} else {
+			t.aggregate(int(n._left), from, nil, fold)
+			fold(ix, false)
+			t.aggregate(int(n._right), nil, to, fold)
+			return
+		}
+	}
+}
+
+// augment recalculates aggregates of subtree ix
+func (t *Tree) augment(ix int) {
+	if t.aug == nil || ix == null {
+		return
+	}
+	n := &t.nodes[ix]
+	t.augment(int(n._left))
+	t.augment(int(n._right))
+	t.aug(ix, int(n._left), int(n._right))
+}
@@ -0,0 +1,121 @@
+package tree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type arow struct {
+	price, sum int
+}
+
+type arows []arow
+
+func (a arows) Len() int           { return len(a) }
+func (a arows) Less(i, j int) bool { return a[i].price < a[j].price }
+func (a arows) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+func augmented(rows *arows) func(ix, l, r int) {
+	return func(ix, l, r int) {
+		a := *rows
+		a[ix].sum = a[ix].price
+		if l >= 0 {
+			a[ix].sum += a[l].sum
+		}
+		if r >= 0 {
+			a[ix].sum += a[r].sum
+		}
+	}
+}
+
+func check_sums(t *testing.T, rows arows, tree *Tree, ix int) int {
+	if ix == null {
+		return 0
+	}
+	n := &tree.nodes[ix]
+	sum := rows[ix].price + check_sums(t, rows, tree, int(n._left)) +
+		check_sums(t, rows, tree, int(n._right))
+	if rows[ix].sum != sum {
+		t.Fatalf("node %d: sum is %d, want %d", ix, rows[ix].sum, sum)
+	}
+	return sum
+}
+
+func check_aggregate(t *testing.T, rows arows, tree *Tree) {
+	for i := 0; i < 20; i++ {
+		a, b := rand.Intn(110)-5, rand.Intn(110)-5
+		want, found := 0, false
+		for _, r := range rows[:tree.Len()] {
+			if r.price >= a && r.price <= b {
+				want += r.price
+				found = true
+			}
+		}
+		got, ok := Aggregate(tree,
+			func(i int) bool { return rows[i].price >= a },
+			func(i int) bool { return rows[i].price <= b },
+			func(ix int, subtree bool) int {
+				if subtree {
+					return rows[ix].sum
+				}
+				return rows[ix].price
+			},
+			func(a, b int) int { return a + b })
+		if got != want || ok != found {
+			t.Fatalf("Aggregate(%d, %d) = %d %v, want %d %v", a, b, got, ok, want, found)
+		}
+	}
+}
+
+func Test_Augment(t *testing.T) {
+	rows := arows{}
+	tree := Tree{}
+	tree.SetAugment(augmented(&rows))
+	for i := 0; i < 1000; i++ {
+		if tree.Len() > 0 && rand.Intn(3) == 0 {
+			if rand.Intn(2) == 0 {
+				tree.Delete(rows, rand.Intn(tree.Len()))
+			} else {
+				tree.DeleteAndPrev(rows, rand.Intn(tree.Len()))
+			}
+			rows = rows[:tree.Len()]
+		} else {
+			rows = append(rows, arow{price: rand.Intn(100)})
+			tree.Insert(rows)
+		}
+		if tree.Len() > 0 {
+			check_sums(t, rows, &tree, tree.root)
+		}
+		check_aggregate(t, rows, &tree)
+	}
+
+	right := tree.Split(rows, func(i int) bool { return rows[i].price >= 50 })
+	check_sums(t, rows, &tree, tree.root)
+	check_aggregate(t, rows, &tree)
+	tree.Join(right)
+	check_sums(t, rows, &tree, tree.root)
+	check_aggregate(t, rows, &tree)
+
+	for i := 0; i < 200; i++ {
+		ix := rand.Intn(tree.Len())
+		if rand.Intn(2) == 0 {
+			rows[ix].price = rand.Intn(100)
+		} else {
+			/* small change, which likely keeps order */
+			rows[ix].price++
+		}
+		tree.Fix(rows, ix)
+		check_sums(t, rows, &tree, tree.root)
+		check_aggregate(t, rows, &tree)
+	}
+
+	tree.DeleteFunc(rows, func(i int) bool { return rows[i].price%7 == 0 })
+	rows = rows[:tree.Len()]
+	check_sums(t, rows, &tree, tree.root)
+	check_aggregate(t, rows, &tree)
+
+	tree.LeaveSorted(rows)
+	tree.InitSorted(rows.Len())
+	check_sums(t, rows, &tree, tree.root)
+	check_aggregate(t, rows, &tree)
+}
@@ -0,0 +1,174 @@
+package tree
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+)
+
+// batchRatio is how many times tree may be larger than batch,
+// for InsertBatch to rebuild it instead of inserting one by one
+const batchRatio = 4
+
+// InsertBatch adds n elements of data at indices from Len() to Len()+n-1,
+// as if Insert were called for each of them: duplicates are inserted in
+// stable order, and no element of data is moved.
+//
+// If batch is small compared to tree, its elements are just inserted
+// one by one. Otherwise indices of batch are sorted, merged with
+// elements of tree, and tree is rebuilt once in O(Len()+n).
+func (t *Tree) InsertBatch(data sort.Interface, n int) {
+	k := len(t.nodes)
+	if n < 0 || k+n > data.Len() {
+		panic(ErrOutOfRange)
+	}
+	if k+n > MaxSize {
+		panic(ErrCapacity)
+	}
+	if n == 0 {
+		return
+	}
+	if k > n*batchRatio {
+		for i := 0; i < n; i++ {
+			t.Insert(data)
+		}
+		return
+	}
+	batch := make([]index, n)
+	for i := range batch {
+		batch[i] = index(k + i)
+	}
+	slices.SortFunc(batch, func(a, b index) int {
+		switch {
+		case data.Less(int(a), int(b)):
+			return -1
+		case data.Less(int(b), int(a)):
+			return 1
+		}
+		return cmp.Compare(a, b)
+	})
+	/* equal elements of tree precede elements of batch */
+	order := make([]index, 0, k+n)
+	if k > 0 {
+		t.walk(t.root, func(x int) {
+			for len(batch) > 0 && data.Less(int(batch[0]), x) {
+				order, batch = append(order, batch[0]), batch[1:]
+			}
+			order = append(order, index(x))
+		})
+	}
+	order = append(order, batch...)
+	for i := 0; i < n; i++ {
+		t.push()
+	}
+	root, _ := t.build(order, null)
+	t.setroot(int(root))
+}
+
+// DeleteFunc removes all elements for which pred returns true
+// and returns number of removed elements.
+//
+// As with Delete, removed elements are moved to the end of data, so that
+// after DeleteFunc they occupy data[t.Len():] and could be truncated.
+// Elements are compacted with minimal number of swaps: only remaining
+// elements from the tail are moved into places of removed ones.
+// If data implements Relocator, it is notified about every moved element.
+//
+// Tree is rebuilt once, so DeleteFunc takes O(n) regardless of number
+// of removed elements.
+func (t *Tree) DeleteFunc(data sort.Interface, pred func(i int) bool) int {
+	del := make([]bool, len(t.nodes))
+	k := 0
+	for i := range del {
+		if pred(i) {
+			del[i] = true
+			k++
+		}
+	}
+	return t.deleteMarked(data, del, k)
+}
+
+// DeleteRange removes elements in the same bounds as visited by Range
+// and returns number of removed elements. Data is compacted as in
+// DeleteFunc.
+func (t *Tree) DeleteRange(data sort.Interface, from, to func(i int) bool) int {
+	del := make([]bool, len(t.nodes))
+	k := 0
+	for ix := range t.Range(from, to) {
+		del[ix] = true
+		k++
+	}
+	return t.deleteMarked(data, del, k)
+}
+
+// deleteMarked removes k elements marked in del
+func (t *Tree) deleteMarked(data sort.Interface, del []bool, k int) int {
+	if k == 0 {
+		return 0
+	}
+	n := len(t.nodes)
+	m := n - k
+	order := make([]index, 0, m)
+	t.walk(t.root, func(ix int) {
+		if !del[ix] {
+			order = append(order, index(ix))
+		}
+	})
+	t.own()
+
+	/* move remaining elements from the tail to holes */
+	rel, _ := data.(Relocator)
+	moved := make([]index, k)
+	for i, j := 0, m; ; i, j = i+1, j+1 {
+		for i < m && !del[i] {
+			i++
+		}
+		if i == m {
+			break
+		}
+		for del[j] {
+			j++
+		}
+		t.swap(data, i, j)
+		if rel != nil {
+			rel.Relocate(j, i)
+		}
+		moved[j-m] = index(i)
+	}
+	for p, ix := range order {
+		if int(ix) >= m {
+			order[p] = moved[int(ix)-m]
+		}
+	}
+
+	if t.h != nil {
+		for i := n; i > m; i-- {
+			t.h.remove()
+		}
+	}
+	t.nodes = t.nodes[:m]
+	root, _ := t.build(order, null)
+	t.setroot(int(root))
+	return k
+}
+
+// build makes balanced subtree of nodes listed in their in-order,
+// as initSorted does for consecutive nodes
+func (t *Tree) build(order []index, p index) (m index, d int8) {
+	if len(order) == 0 {
+		return null, 0
+	}
+	h := len(order) / 2
+	m = order[h]
+	n := &t.nodes[m]
+	n._parent = p
+	var dl, dr int8
+	n._left, dl = t.build(order[:h], m)
+	n._right, dr = t.build(order[h+1:], m)
+	n.height = max_i8(dl, dr) + 1
+	n.size = index(len(order))
+	if t.aug != nil {
+		t.aug(int(m), int(n._left), int(n._right))
+	}
+	return m, n.height
+}
@@ -0,0 +1,465 @@
+package tree
+
+import (
+	"iter"
+	"math"
+	"sort"
+)
+
+// CompactMaxSize is maximal number of elements kept in compact layout
+const CompactMaxSize = math.MaxUint16
+
+const cnull = math.MaxUint16
+
+// Compact is a tree for small indices, which keeps nodes in compact
+// layout: three uint16 links and height take 8 bytes per element
+// instead of 20 bytes used by Tree (40 with tree64 tag), and Compact
+// itself is smaller than Tree.
+//
+// When Insert exceeds CompactMaxSize elements, Compact is promoted
+// to Tree layout transparently, and all methods work with it further.
+//
+// Compact supports basic operations only: no handles, augmentation,
+// rank and snapshots. Zero value is an empty tree.
+type Compact struct {
+	root, min, max uint16
+	nodes          []cnode
+	big            *Tree // tree after promotion
+}
+
+type cnode struct {
+	_parent uint16
+	_left   uint16
+	_right  uint16
+	height  uint8
+}
+
+// clink converts index to link, null becomes cnull
+func clink(ix int) uint16 {
+	return uint16(ix)
+}
+
+// cindex converts link to index, cnull becomes null
+func cindex(l uint16) int {
+	if l == cnull {
+		return null
+	}
+	return int(l)
+}
+
+func (n *cnode) link(i direction) int {
+	if i == right {
+		return cindex(n._right)
+	}
+	return cindex(n._left)
+}
+
+func (n *cnode) set_link(i direction, ix int) {
+	if i == right {
+		n._right = clink(ix)
+	} else {
+		n._left = clink(ix)
+	}
+}
+
+// Len returns number of indexed elements
+func (c *Compact) Len() int {
+	if c.big != nil {
+		return c.big.Len()
+	}
+	return len(c.nodes)
+}
+
+// Promoted reports if tree was promoted to Tree layout
+func (c *Compact) Promoted() bool {
+	return c.big != nil
+}
+
+// Min returns index of minimal element
+// panics if called on empty tree
+func (c *Compact) Min() int {
+	if c.big != nil {
+		return c.big.Min()
+	}
+	if len(c.nodes) == 0 {
+		panic(ErrEmpty)
+	}
+	return int(c.min)
+}
+
+// Max returns index of maximal element
+// panics if called on empty tree
+func (c *Compact) Max() int {
+	if c.big != nil {
+		return c.big.Max()
+	}
+	if len(c.nodes) == 0 {
+		panic(ErrEmpty)
+	}
+	return int(c.max)
+}
+
+// Search is the same as Tree.Search
+func (c *Compact) Search(pred func(i int) bool) int {
+	if c.big != nil {
+		return c.big.Search(pred)
+	}
+	res := len(c.nodes)
+	for now := c.top(); now != null; {
+		node := &c.nodes[now]
+		if pred(now) {
+			res = now
+			now = cindex(node._left)
+		} else {
+			now = cindex(node._right)
+		}
+	}
+	return res
+}
+
+// SearchLast is the same as Tree.SearchLast
+func (c *Compact) SearchLast(pred func(i int) bool) int {
+	if c.big != nil {
+		return c.big.SearchLast(pred)
+	}
+	res := -1
+	for now := c.top(); now != null; {
+		node := &c.nodes[now]
+		if pred(now) {
+			res = now
+			now = cindex(node._right)
+		} else {
+			now = cindex(node._left)
+		}
+	}
+	return res
+}
+
+// Next is the same as Tree.Next
+func (c *Compact) Next(i int) int {
+	if c.big != nil {
+		return c.big.Next(i)
+	}
+	n := len(c.nodes)
+	if i < -1 || i > n {
+		panic(ErrOutOfRange)
+	}
+	if n == 0 || i == n || i == int(c.max) {
+		return n
+	}
+	if i == -1 {
+		return int(c.min)
+	}
+	return c.step(i, right)
+}
+
+// Prev is the same as Tree.Prev
+func (c *Compact) Prev(i int) int {
+	if c.big != nil {
+		return c.big.Prev(i)
+	}
+	n := len(c.nodes)
+	if i < -1 || i > n {
+		panic(ErrOutOfRange)
+	}
+	if n == 0 || i == -1 || i == int(c.min) {
+		return -1
+	}
+	if i == n {
+		return int(c.max)
+	}
+	return c.step(i, left)
+}
+
+// All returns iterator over indices of elements in order
+func (c *Compact) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for ix := c.Next(-1); ix < c.Len(); ix = c.Next(ix) {
+			if !yield(ix) {
+				return
+			}
+		}
+	}
+}
+
+// Insert adds in-order element of sort.Interface at index Len(),
+// as Tree.Insert does. Tree is promoted to Tree layout, when
+// it exceeds CompactMaxSize.
+func (c *Compact) Insert(data sort.Interface) {
+	if c.big == nil && len(c.nodes) == CompactMaxSize {
+		c.promote()
+	}
+	if c.big != nil {
+		c.big.Insert(data)
+		return
+	}
+	ix := len(c.nodes)
+	c.nodes = append(c.nodes, cnode{cnull, cnull, cnull, 1})
+	if ix == 0 {
+		c.root, c.min, c.max = 0, 0, 0
+		return
+	}
+	cur := int(c.root)
+	var dir direction
+	for {
+		dir = direction(!data.Less(ix, cur))
+		next := c.nodes[cur].link(dir)
+		if next == null {
+			break
+		}
+		cur = next
+	}
+	c.nodes[ix]._parent = clink(cur)
+	c.nodes[cur].set_link(dir, ix)
+	if dir == right && cur == int(c.max) {
+		c.max = clink(ix)
+	} else if dir == left && cur == int(c.min) {
+		c.min = clink(ix)
+	}
+	c.balance(cur)
+}
+
+// Delete removes element ix as Tree.Delete does: element from last
+// index is moved to ix, so removed element ends at index Len().
+// No other element is moved, also after promotion.
+// If data implements Relocator, it is notified about moved element.
+// Returns index of next element.
+func (c *Compact) Delete(data sort.Interface, ix int) int {
+	last := c.Len() - 1
+	if ix < 0 || ix > last {
+		panic(ErrOutOfRange)
+	}
+	next := c.Next(ix)
+	if ix != last {
+		data.Swap(ix, last)
+		if rel, ok := data.(Relocator); ok {
+			rel.Relocate(last, ix)
+		}
+	}
+	if t := c.big; t != nil {
+		/* as MultiIndex.Delete does, to not move other elements */
+		t.swapnodes(ix, last)
+		t.detach(last)
+		t.nodes = t.nodes[:last]
+	} else {
+		c.detach(ix)
+		if ix != last {
+			c.relabel(last, ix)
+		}
+		c.nodes = c.nodes[:last]
+	}
+	if next == last {
+		next = ix
+	} else if next == last+1 {
+		next = last
+	}
+	return next
+}
+
+// top returns root or null for empty tree
+func (c *Compact) top() int {
+	if len(c.nodes) == 0 {
+		return null
+	}
+	return int(c.root)
+}
+
+// step returns in-order neighbour of ix in direction dir
+func (c *Compact) step(ix int, dir direction) int {
+	if ch := c.nodes[ix].link(dir); ch != null {
+		for ix = ch; c.nodes[ix].link(!dir) != null; {
+			ix = c.nodes[ix].link(!dir)
+		}
+		return ix
+	}
+	for {
+		p := cindex(c.nodes[ix]._parent)
+		if p == null {
+			panic(ErrCorrupt)
+		}
+		if c.nodes[p].link(!dir) == ix {
+			return p
+		}
+		ix = p
+	}
+}
+
+// detach removes node ix from tree structure, as Tree.detach does
+func (c *Compact) detach(ix int) {
+	n := &c.nodes[ix]
+	l, r := cindex(n._left), cindex(n._right)
+	var start int
+	if l != null && r != null {
+		s := r
+		for c.nodes[s]._left != cnull {
+			s = int(c.nodes[s]._left)
+		}
+		snode := &c.nodes[s]
+		start = cindex(snode._parent)
+		if start == ix {
+			start = s
+		} else {
+			c.nodes[start]._left = snode._right
+			if snode._right != cnull {
+				c.nodes[snode._right]._parent = clink(start)
+			}
+			snode._right = clink(r)
+			c.nodes[r]._parent = clink(s)
+		}
+		snode._left = clink(l)
+		c.nodes[l]._parent = clink(s)
+		c.replace(ix, s)
+	} else {
+		ch := l
+		if ch == null {
+			ch = r
+		}
+		start = cindex(n._parent)
+		c.replace(ix, ch)
+	}
+	*n = cnode{cnull, cnull, cnull, 1}
+	c.balance(start)
+	if c.root == cnull {
+		return
+	}
+	if int(c.min) == ix {
+		c.min = c.root
+		for c.nodes[c.min]._left != cnull {
+			c.min = c.nodes[c.min]._left
+		}
+	}
+	if int(c.max) == ix {
+		c.max = c.root
+		for c.nodes[c.max]._right != cnull {
+			c.max = c.nodes[c.max]._right
+		}
+	}
+}
+
+// replace puts subtree ch at place of node ix in its parent
+func (c *Compact) replace(ix, ch int) {
+	p := cindex(c.nodes[ix]._parent)
+	if ch != null {
+		c.nodes[ch]._parent = clink(p)
+	}
+	if p == null {
+		c.root = clink(ch)
+	} else if int(c.nodes[p]._left) == ix {
+		c.nodes[p]._left = clink(ch)
+	} else {
+		c.nodes[p]._right = clink(ch)
+	}
+}
+
+// relabel moves node from index i to unused index j
+func (c *Compact) relabel(i, j int) {
+	n := c.nodes[i]
+	c.nodes[j] = n
+	if p := cindex(n._parent); p == null {
+		c.root = clink(j)
+	} else if int(c.nodes[p]._left) == i {
+		c.nodes[p]._left = clink(j)
+	} else {
+		c.nodes[p]._right = clink(j)
+	}
+	if n._left != cnull {
+		c.nodes[n._left]._parent = clink(j)
+	}
+	if n._right != cnull {
+		c.nodes[n._right]._parent = clink(j)
+	}
+	if int(c.min) == i {
+		c.min = clink(j)
+	}
+	if int(c.max) == i {
+		c.max = clink(j)
+	}
+}
+
+func (c *Compact) height(ix int) uint8 {
+	if ix == null {
+		return 0
+	}
+	return c.nodes[ix].height
+}
+
+func (c *Compact) fix(ix int) {
+	n := &c.nodes[ix]
+	lh, rh := c.height(cindex(n._left)), c.height(cindex(n._right))
+	n.height = max(lh, rh) + 1
+}
+
+// balance restores balance from cur to root, as Tree.balance does
+func (c *Compact) balance(cur int) {
+	for cur != null {
+		node := &c.nodes[cur]
+		lh, rh := int(c.height(cindex(node._left))), int(c.height(cindex(node._right)))
+		var dir direction
+		if lh < rh-1 {
+			dir = right
+		} else if lh-1 > rh {
+			dir = left
+		} else {
+			c.fix(cur)
+			cur = cindex(node._parent)
+			continue
+		}
+		ch := node.link(dir)
+		chnode := &c.nodes[ch]
+		if c.height(chnode.link(dir)) < c.height(chnode.link(!dir)) {
+			/* rotate child */
+			c.rotate(ch, !dir)
+		}
+		c.rotate(cur, dir)
+		cur = cindex(node._parent)
+	}
+}
+
+func (c *Compact) rotate(ix int, dir direction) {
+	node := &c.nodes[ix]
+	p := cindex(node._parent)
+	ch := node.link(dir)
+	chnode := &c.nodes[ch]
+	node.set_link(dir, chnode.link(!dir))
+	if g := node.link(dir); g != null {
+		c.nodes[g]._parent = clink(ix)
+	}
+	chnode.set_link(!dir, ix)
+	node._parent = clink(ch)
+	chnode._parent = clink(p)
+	c.fix(ix)
+	c.fix(ch)
+	if p == null {
+		c.root = clink(ch)
+		return
+	}
+	pnode := &c.nodes[p]
+	pnode.set_link(direction(int(pnode._right) == ix), ch)
+	c.fix(p)
+}
+
+// tree converts compact layout to Tree
+func (c *Compact) tree() *Tree {
+	t := &Tree{root: c.top(), min: c.top(), max: c.top(), nodes: make([]node, len(c.nodes))}
+	if len(c.nodes) > 0 {
+		t.min, t.max = int(c.min), int(c.max)
+	}
+	for i, n := range c.nodes {
+		t.nodes[i] = node{
+			_parent: index(cindex(n._parent)),
+			_left:   index(cindex(n._left)),
+			_right:  index(cindex(n._right)),
+			height:  int8(n.height),
+		}
+	}
+	if err := t.validate(true); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// promote switches to Tree layout
+func (c *Compact) promote() {
+	c.big = c.tree()
+	c.nodes = nil
+}
@@ -0,0 +1,147 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"unsafe"
+)
+
+func Test_Compact(t *testing.T) {
+	for k := 0; k < 50; k++ {
+		data := relslice{sort.IntSlice{}, map[int]int{}}
+		c := Compact{}
+		for i := 0; i < 500; i++ {
+			if c.Len() > 0 && rand.Intn(3) == 0 {
+				ix := rand.Intn(c.Len())
+				v := data.IntSlice[ix]
+				next := c.Delete(data, ix)
+				if data.IntSlice[c.Len()] != v {
+					t.Fatalf("Delete don't place value at last position")
+				}
+				delete(data.pos, v)
+				data.IntSlice = data.IntSlice[:c.Len()]
+				if next < c.Len() && data.IntSlice[next] < v {
+					t.Fatalf("Delete returns %d before deleted %d", data.IntSlice[next], v)
+				}
+				if p := c.Prev(next); p >= 0 && data.IntSlice[p] > v {
+					t.Fatalf("Delete skips %d", data.IntSlice[p])
+				}
+			} else {
+				v := rand.Intn(100)*1000 + i
+				data.IntSlice = append(data.IntSlice, v)
+				data.pos[v] = len(data.IntSlice) - 1
+				c.Insert(data)
+			}
+			if err := c.tree().Verify(data); err != nil {
+				t.Fatal(err)
+			}
+			for i, v := range data.IntSlice {
+				if data.pos[v] != i {
+					t.Fatalf("position of %d is %d, reported %d", v, i, data.pos[v])
+				}
+			}
+		}
+		sorted := append([]int(nil), data.IntSlice...)
+		sort.Ints(sorted)
+		i := 0
+		for ix := range c.All() {
+			if data.IntSlice[ix] != sorted[i] {
+				t.Fatalf("All: %d at %d, want %d", data.IntSlice[ix], i, sorted[i])
+			}
+			i++
+		}
+		if c.Len() > 0 {
+			v := sorted[len(sorted)/2]
+			if ix := c.Search(func(i int) bool { return data.IntSlice[i] >= v }); data.IntSlice[ix] != v {
+				t.Fatalf("Search(%d) = %d", v, data.IntSlice[ix])
+			}
+			if ix := c.SearchLast(func(i int) bool { return data.IntSlice[i] <= v }); data.IntSlice[ix] != v {
+				t.Fatalf("SearchLast(%d) = %d", v, data.IntSlice[ix])
+			}
+			if data.IntSlice[c.Min()] != sorted[0] || data.IntSlice[c.Max()] != sorted[len(sorted)-1] {
+				t.Fatalf("Min/Max mismatch")
+			}
+		}
+	}
+}
+
+func Test_CompactPromote(t *testing.T) {
+	data := sort.IntSlice{}
+	c := Compact{}
+	for i := 0; i <= CompactMaxSize; i++ {
+		data = append(data, rand.Intn(1000))
+		c.Insert(data)
+	}
+	if !c.Promoted() || c.Len() != CompactMaxSize+1 {
+		t.Fatalf("tree of %d elements is not promoted", c.Len())
+	}
+	if err := c.big.Verify(data); err != nil {
+		t.Fatal(err)
+	}
+	for c.Len() > 1000 {
+		c.Delete(data, rand.Intn(c.Len()))
+		data = data[:c.Len()]
+	}
+	if err := c.big.Verify(data); err != nil {
+		t.Fatal(err)
+	}
+	if !sort.IsSorted(sortedBy(&c, data)) {
+		t.Fatalf("promoted tree is not in order")
+	}
+	if !c.Promoted() {
+		t.Fatalf("tree is demoted")
+	}
+
+	/* without Relocator only last element may move */
+	ids := make([]int, len(data))
+	for i := range ids {
+		ids[i] = i
+	}
+	for k := 0; k < 100; k++ {
+		ix := c.big.root
+		if k%2 == 1 {
+			ix = rand.Intn(c.Len())
+		}
+		last := c.Len() - 1
+		c.Delete(idslice{data, ids}, ix)
+		data, ids = data[:last], ids[:last]
+		for i, id := range ids {
+			if id != i && !(i == ix && id == last) {
+				t.Fatalf("Delete(%d) moved element %d to %d", ix, id, i)
+			}
+		}
+		if ix < last {
+			ids[ix] = ix
+		}
+	}
+	if err := c.big.Verify(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_CompactSize(t *testing.T) {
+	if s := unsafe.Sizeof(cnode{}); s != 8 {
+		t.Fatalf("compact node takes %d bytes", s)
+	}
+}
+
+// sortedBy returns elements of data in order of c
+func sortedBy(c *Compact, data sort.IntSlice) sort.IntSlice {
+	var res sort.IntSlice
+	for ix := range c.All() {
+		res = append(res, data[ix])
+	}
+	return res
+}
+
+// idslice keeps identities of elements of IntSlice
+type idslice struct {
+	sort.IntSlice
+	ids []int
+}
+
+func (s idslice) Swap(i, j int) {
+	s.IntSlice.Swap(i, j)
+	s.ids[i], s.ids[j] = s.ids[j], s.ids[i]
+}
@@ -0,0 +1,27 @@
+package tree
+
+import "errors"
+
+// Errors returned by Try* methods, Verify and UnmarshalBinary.
+// Panicking methods panic with the same values.
+var (
+	// ErrEmpty is returned when element is requested from empty tree
+	ErrEmpty = errors.New("tree: empty tree")
+	// ErrOutOfRange is returned for index out of tree bounds
+	ErrOutOfRange = errors.New("tree: index out of range")
+	// ErrCapacity is returned when tree size would exceed MaxSize
+	ErrCapacity = errors.New("tree: size exceed maximum")
+	// ErrCorrupt is returned when tree structure is broken
+	ErrCorrupt = errors.New("tree: corrupted")
+	// ErrNoHandles is returned when handles are requested,
+	// but not enabled
+	ErrNoHandles = errors.New("tree: handles are not enabled")
+)
+
+// must panics on error
+func must(ix int, err error) int {
+	if err != nil {
+		panic(err)
+	}
+	return ix
+}
@@ -0,0 +1,149 @@
+package tree
+
+import "cmp"
+
+// Func is a balanced tree which owns its elements and orders them
+// with comparison function. It uses the same index structure as Tree,
+// but compares values directly instead of calling sort.Interface.
+//
+//     set := tree.NewOrdered[int]()
+//     set.Insert(5)
+//     set.Insert(3)
+//     v, ok := set.Find(3)
+//
+// Func should be created with NewFunc or NewOrdered.
+type Func[T any] struct {
+	tree  Tree
+	items []T
+	cmp   func(a, b T) int
+}
+
+// NewFunc returns empty tree ordered by cmp.
+// cmp should return negative number if a < b, positive if a > b
+// and zero if they are equal.
+func NewFunc[T any](cmp func(a, b T) int) *Func[T] {
+	return &Func[T]{cmp: cmp}
+}
+
+// NewOrdered returns empty tree of naturally ordered values.
+func NewOrdered[T cmp.Ordered]() *Func[T] {
+	return NewFunc(cmp.Compare[T])
+}
+
+// Len returns number of elements
+func (f *Func[T]) Len() int {
+	return len(f.items)
+}
+
+// Insert adds value to a tree.
+// It doesn't check for equality, so duplicates are inserted in
+// stable order.
+func (f *Func[T]) Insert(v T) {
+	f.items = append(f.items, v)
+	t := &f.tree
+	ix := t.push()
+	if ix == 0 {
+		return
+	}
+	cur := t.root
+	for {
+		dir := direction(f.cmp(v, f.items[cur]) >= 0)
+		next := t.nodes[cur].link(dir)
+		if next == null {
+			t.attach(cur, dir, ix)
+			return
+		}
+		cur = next
+	}
+}
+
+// Delete removes first element equal to v.
+// Returns false if there is no such element.
+func (f *Func[T]) Delete(v T) bool {
+	ix := f.find(v)
+	if ix == len(f.items) {
+		return false
+	}
+	f.tree.Delete(funcSlice[T]{f}, ix)
+	var zero T
+	f.items[len(f.items)-1] = zero
+	f.items = f.items[:len(f.items)-1]
+	return true
+}
+
+// Find returns first element equal to v
+func (f *Func[T]) Find(v T) (T, bool) {
+	ix := f.find(v)
+	if ix == len(f.items) {
+		var zero T
+		return zero, false
+	}
+	return f.items[ix], true
+}
+
+// Search returns first in-order element for which predicate is true.
+// Predicate should be false for some (possibly empty) prefix
+// and true for the rest, like in sort.Search.
+func (f *Func[T]) Search(pred func(v T) bool) (T, bool) {
+	ix := f.tree.Search(func(i int) bool {
+		return pred(f.items[i])
+	})
+	if ix == len(f.items) {
+		var zero T
+		return zero, false
+	}
+	return f.items[ix], true
+}
+
+// Min returns minimal element
+// panics if called on empty tree
+func (f *Func[T]) Min() T {
+	return f.items[f.tree.Min()]
+}
+
+// Max returns maximal element
+// panics if called on empty tree
+func (f *Func[T]) Max() T {
+	return f.items[f.tree.Max()]
+}
+
+// Ascend calls fn for every element in order until fn returns false
+func (f *Func[T]) Ascend(fn func(v T) bool) {
+	t := &f.tree
+	if t.Len() == 0 {
+		return
+	}
+	for ix := t.Min(); ix < t.Len(); ix = t.Next(ix) {
+		if !fn(f.items[ix]) {
+			return
+		}
+	}
+}
+
+// Descend calls fn for every element in reverse order until fn returns false
+func (f *Func[T]) Descend(fn func(v T) bool) {
+	t := &f.tree
+	if t.Len() == 0 {
+		return
+	}
+	for ix := t.Max(); ix >= 0; ix = t.Prev(ix) {
+		if !fn(f.items[ix]) {
+			return
+		}
+	}
+}
+
+func (f *Func[T]) find(v T) int {
+	return f.tree.Find(func(i int) int {
+		return f.cmp(f.items[i], v)
+	})
+}
+
+// funcSlice exposes Func elements as sort.Interface for Tree methods
+type funcSlice[T any] struct {
+	f *Func[T]
+}
+
+func (s funcSlice[T]) Len() int           { return len(s.f.items) }
+func (s funcSlice[T]) Less(i, j int) bool { return s.f.cmp(s.f.items[i], s.f.items[j]) < 0 }
+func (s funcSlice[T]) Swap(i, j int)      { s.f.items[i], s.f.items[j] = s.f.items[j], s.f.items[i] }
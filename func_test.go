@@ -0,0 +1,86 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_Func(t *testing.T) {
+	f := NewOrdered[int]()
+	ref := []int{}
+	for i := 0; i < 1000; i++ {
+		v := rand.Intn(200)
+		if rand.Intn(3) == 0 {
+			_, found := f.Find(v)
+			j := sort.SearchInts(ref, v)
+			has := j < len(ref) && ref[j] == v
+			if found != has {
+				t.Fatalf("Find(%d) = %v, want %v", v, found, has)
+			}
+			if f.Delete(v) != has {
+				t.Fatalf("Delete(%d) != %v", v, has)
+			}
+			if has {
+				ref = append(ref[:j], ref[j+1:]...)
+			}
+		} else {
+			f.Insert(v)
+			j := sort.SearchInts(ref, v+1)
+			ref = append(ref[:j], append([]int{v}, ref[j:]...)...)
+		}
+		if f.Len() != len(ref) {
+			t.Fatalf("Len() = %d, want %d", f.Len(), len(ref))
+		}
+		if f.Len() > 0 {
+			check(t, funcSlice[int]{f}, &f.tree, f.tree.root)
+		}
+	}
+	k := 0
+	f.Ascend(func(v int) bool {
+		if v != ref[k] {
+			t.Fatalf("Ascend: %d != %d", v, ref[k])
+		}
+		k++
+		return true
+	})
+	if k != len(ref) {
+		t.Fatalf("Ascend visited %d of %d", k, len(ref))
+	}
+	f.Descend(func(v int) bool {
+		k--
+		if v != ref[k] {
+			t.Fatalf("Descend: %d != %d", v, ref[k])
+		}
+		return true
+	})
+	if f.Min() != ref[0] || f.Max() != ref[len(ref)-1] {
+		t.Fatalf("Min/Max mismatch")
+	}
+	v, ok := f.Search(func(v int) bool { return v >= 100 })
+	j := sort.SearchInts(ref, 100)
+	if ok != (j < len(ref)) || ok && v != ref[j] {
+		t.Fatalf("Search: %d %v", v, ok)
+	}
+}
+
+func Test_FuncStable(t *testing.T) {
+	f := NewFunc(func(a, b string) int {
+		return strings.Compare(a[:1], b[:1])
+	})
+	for _, s := range []string{"b1", "a1", "b2", "a2", "b3"} {
+		f.Insert(s)
+	}
+	var got []string
+	f.Ascend(func(s string) bool {
+		got = append(got, s)
+		return true
+	})
+	if strings.Join(got, " ") != "a1 a2 b1 b2 b3" {
+		t.Fatalf("unstable order: %v", got)
+	}
+	if v, _ := f.Find("b"); v != "b1" {
+		t.Fatalf("Find returns %q, want first equal", v)
+	}
+}
@@ -0,0 +1,141 @@
+package tree
+
+// Handle is a stable reference to an element of a Tree.
+// Unlike index, it doesn't change when Delete moves elements around.
+//
+// Slot of deleted element may be reused by later insertion, but
+// handle keeps generation of slot in its high bits, so that stale
+// handle doesn't point to new element.
+type Handle int64
+
+const (
+	handleSlot = 1<<handleBits - 1
+	handleGen  = 1<<(63-handleBits) - 1
+)
+
+type handles struct {
+	pos    []index  // element index by slot, null for free slot
+	of     []index  // slot by element index
+	gen    []uint32 // generation of slot
+	free   []index
+	sorted bool // pos of free slots is set by LeaveSorted
+}
+
+// EnableHandles turns on tracking of handles.
+// Existing elements get handles equal to their indices.
+func (t *Tree) EnableHandles() {
+	if t.h != nil {
+		return
+	}
+	t.h = &handles{}
+	t.h.reset(len(t.nodes))
+}
+
+// Handle returns handle of element ix
+// panics if handles are not enabled
+func (t *Tree) Handle(ix int) Handle {
+	if t.h == nil {
+		panic(ErrNoHandles)
+	}
+	if ix < 0 || ix >= len(t.nodes) {
+		panic(ErrOutOfRange)
+	}
+	return t.h.handle(t.h.of[ix])
+}
+
+// Index returns current index of element referenced by handle.
+// returns -1 if element was deleted.
+func (t *Tree) Index(h Handle) int {
+	if t.h == nil || h < 0 {
+		return -1
+	}
+	slot, gen := int64(h)&handleSlot, int64(h)>>handleBits
+	if slot >= int64(len(t.h.pos)) || int64(t.h.gen[slot]) != gen {
+		return -1
+	}
+	return int(t.h.pos[slot])
+}
+
+// handle makes handle of slot with its current generation
+func (h *handles) handle(slot index) Handle {
+	return Handle(int64(h.gen[slot])<<handleBits | int64(slot))
+}
+
+// bump invalidates handles of slot
+func (h *handles) bump(slot index) {
+	h.gen[slot] = (h.gen[slot] + 1) & handleGen
+}
+
+// reset assigns identity slots to n elements.
+// Handles given before are invalidated.
+func (h *handles) reset(n int) {
+	for i := range h.gen {
+		h.bump(index(i))
+	}
+	for len(h.gen) < n {
+		h.gen = append(h.gen, 0)
+	}
+	h.pos = make([]index, len(h.gen))
+	h.of = make([]index, n)
+	h.free = h.free[:0]
+	for i := range h.pos {
+		if i < n {
+			h.pos[i], h.of[i] = index(i), index(i)
+		} else {
+			h.pos[i] = null
+			h.free = append(h.free, index(i))
+		}
+	}
+	h.sorted = false
+}
+
+// add assigns handle to new element ix
+func (h *handles) add(ix int) {
+	if h.sorted {
+		for _, hd := range h.free {
+			h.pos[hd] = null
+			h.bump(hd)
+		}
+		h.sorted = false
+	}
+	var hd index
+	if n := len(h.free); n > 0 {
+		hd, h.free = h.free[n-1], h.free[:n-1]
+	} else {
+		if int64(len(h.pos)) > handleSlot {
+			panic(ErrCapacity)
+		}
+		hd = index(len(h.pos))
+		h.pos = append(h.pos, 0)
+		h.gen = append(h.gen, 0)
+	}
+	h.pos[hd] = index(ix)
+	h.of = append(h.of, hd)
+}
+
+func (h *handles) swap(i, j int) {
+	h.of[i], h.of[j] = h.of[j], h.of[i]
+	h.pos[h.of[i]], h.pos[h.of[j]] = index(i), index(j)
+}
+
+// remove frees handle of last element
+func (h *handles) remove() {
+	n := len(h.of) - 1
+	hd := h.of[n]
+	h.of = h.of[:n]
+	h.pos[hd] = null
+	h.bump(hd)
+	h.free = append(h.free, hd)
+}
+
+// leave restores positions and generations of n elements freed
+// by LeaveSorted. They were deleted from maximal, so that last freed
+// is at 0. They are invalidated again by next add.
+func (h *handles) leave(n int) {
+	freed := h.free[len(h.free)-n:]
+	for i, hd := range freed {
+		h.pos[hd] = index(n - 1 - i)
+		h.gen[hd] = (h.gen[hd] - 1) & handleGen
+	}
+	h.sorted = true
+}
@@ -0,0 +1,125 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_Handles(t *testing.T) {
+	for k := 0; k < 50; k++ {
+		data := sort.IntSlice{}
+		tree := Tree{}
+		for i := 0; i < 50; i++ {
+			data = append(data, i)
+			tree.Insert(data)
+		}
+		tree.EnableHandles()
+		live := map[Handle]int{}
+		for ix := 0; ix < tree.Len(); ix++ {
+			live[tree.Handle(ix)] = data[ix]
+		}
+		var dead []Handle
+		for i := 0; i < 300; i++ {
+			if tree.Len() > 0 && rand.Intn(2) == 0 {
+				ix := rand.Intn(tree.Len())
+				h := tree.Handle(ix)
+				if rand.Intn(2) == 0 {
+					tree.Delete(data, ix)
+				} else {
+					tree.DeleteAndPrev(data, ix)
+				}
+				data = data[:tree.Len()]
+				if tree.Index(h) != -1 {
+					t.Fatalf("handle of deleted element is valid")
+				}
+				delete(live, h)
+				dead = append(dead, h)
+			} else {
+				data = append(data, 50+i)
+				tree.Insert(data)
+				ix := tree.Len() - 1
+				live[tree.Handle(ix)] = data[ix]
+			}
+			for h, v := range live {
+				if ix := tree.Index(h); data[ix] != v {
+					t.Fatalf("handle %d points to %d, want %d", h, data[ix], v)
+				}
+			}
+			/* slots are reused, but stale handles are not */
+			for _, h := range dead {
+				if ix := tree.Index(h); ix != -1 {
+					t.Fatalf("stale handle %d points to %d", h, data[ix])
+				}
+			}
+		}
+		tree.LeaveSorted(data)
+		if !sort.IsSorted(data) {
+			t.Fatalf("not sorted")
+		}
+		for h, v := range live {
+			if ix := tree.Index(h); data[ix] != v {
+				t.Fatalf("after LeaveSorted handle %d points to %d, want %d", h, data[ix], v)
+			}
+		}
+		data = append(data[:0], 1)
+		tree.Insert(data)
+		for h := range live {
+			if tree.Index(h) != -1 {
+				t.Fatalf("stale handle after LeaveSorted")
+			}
+		}
+	}
+}
+
+func Test_HandlesSplit(t *testing.T) {
+	data := sort.IntSlice{}
+	tree := Tree{}
+	tree.EnableHandles()
+	for i := 0; i < 100; i++ {
+		data = append(data, rand.Intn(100))
+		tree.Insert(data)
+	}
+	live := map[Handle]int{}
+	for ix := 0; ix < tree.Len(); ix++ {
+		live[tree.Handle(ix)] = data[ix]
+	}
+	right := tree.Split(data, func(i int) bool { return data[i] >= 50 })
+	for h, v := range live {
+		ix := tree.Index(h)
+		if (ix == -1) != (v >= 50) {
+			t.Fatalf("handle of %d after split: %d", v, ix)
+		}
+		if ix >= 0 && data[ix] != v {
+			t.Fatalf("handle %d points to %d, want %d", h, data[ix], v)
+		}
+	}
+	for ix := 0; ix < right.Len(); ix++ {
+		if right.Index(right.Handle(ix)) != ix {
+			t.Fatalf("handles of right tree are broken")
+		}
+	}
+}
+
+func Test_HandlesReuse(t *testing.T) {
+	data := sort.IntSlice{1, 2}
+	tree := Tree{}
+	tree.EnableHandles()
+	tree.Insert(data[:1])
+	tree.Insert(data)
+	h := tree.Handle(1)
+	tree.Delete(data, 1)
+	data = append(data[:1], 99)
+	tree.Insert(data)
+	if ix := tree.Index(h); ix != -1 {
+		t.Fatalf("handle of deleted element points to %d", data[ix])
+	}
+	if tree.Index(tree.Handle(1)) != 1 {
+		t.Fatalf("handle of new element is broken")
+	}
+	old := tree.Handle(0)
+	tree.InitSorted(tree.Len())
+	if tree.Index(old) != -1 {
+		t.Fatalf("handle is valid after InitSorted")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !tree64
+
+package tree
+
+type index int32
+
+// MaxSize is maximal number of elements in a tree
+const MaxSize = (1 << 30) - 1
+
+// handleBits is number of low bits of Handle used for slot
+const handleBits = 32
@@ -0,0 +1,15 @@
+//go:build tree64
+
+package tree
+
+import "math"
+
+type index int64
+
+// MaxSize is maximal number of elements in a tree.
+// With tree64 tag it is limited only by int on the platform.
+const MaxSize = math.MaxInt >> 1
+
+// handleBits is number of low bits of Handle used for slot,
+// so that handles of up to 2**40 elements could be allocated
+const handleBits = 40
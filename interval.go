@@ -0,0 +1,124 @@
+package tree
+
+import (
+	"cmp"
+	"iter"
+	"sort"
+)
+
+// IntervalData is sort.Interface ordered by interval start,
+// which also provides bounds of closed intervals [Start(i), End(i)].
+type IntervalData[T cmp.Ordered] interface {
+	sort.Interface
+	Start(i int) T
+	End(i int) T
+}
+
+// IntervalTree indexes intervals by start and keeps maximal end
+// of every subtree, so that intervals overlapping point or range
+// could be found in O(log n + k) for k found intervals.
+//
+// As Tree, it is an external index for data: element to insert should be
+// appended to data, and deleted element is moved to the end of data.
+//
+//     spans = append(spans, Span{From: 10, To: 20})
+//     index.Insert(spans)
+//     for ix := range index.Stab(spans, 15) {
+//         ...
+//     }
+type IntervalTree[T cmp.Ordered] struct {
+	tree   Tree
+	maxEnd []T
+	data   IntervalData[T] // data of current modification
+}
+
+// Len returns number of indexed intervals
+func (it *IntervalTree[T]) Len() int {
+	return it.tree.Len()
+}
+
+// Insert adds interval at index Len() of data
+func (it *IntervalTree[T]) Insert(data IntervalData[T]) {
+	if it.tree.aug == nil {
+		it.tree.aug = it.update
+	}
+	it.data = data
+	defer func() { it.data = nil }()
+	it.maxEnd = append(it.maxEnd, data.End(it.tree.Len()))
+	it.tree.Insert(intervalSwap[T]{data, it})
+}
+
+// Delete removes interval ix from index, moving it to index Len() of data.
+// If data implements Relocator, it is notified as in Tree.Delete.
+func (it *IntervalTree[T]) Delete(data IntervalData[T], ix int) {
+	it.data = data
+	defer func() { it.data = nil }()
+	var s sort.Interface = intervalSwap[T]{data, it}
+	if rel, ok := data.(Relocator); ok {
+		s = intervalRelocator[T]{intervalSwap[T]{data, it}, rel}
+	}
+	it.tree.Delete(s, ix)
+	it.maxEnd = it.maxEnd[:it.tree.Len()]
+}
+
+// Overlap returns iterator over indices of intervals which overlap
+// closed range [a, b], in order of interval start.
+func (it *IntervalTree[T]) Overlap(data IntervalData[T], a, b T) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		if it.tree.Len() > 0 {
+			it.overlap(data, it.tree.root, a, b, yield)
+		}
+	}
+}
+
+// Stab returns iterator over indices of intervals which contain point p,
+// in order of interval start.
+func (it *IntervalTree[T]) Stab(data IntervalData[T], p T) iter.Seq[int] {
+	return it.Overlap(data, p, p)
+}
+
+func (it *IntervalTree[T]) overlap(data IntervalData[T], ix int, a, b T, yield func(int) bool) bool {
+	for ix != null && it.maxEnd[ix] >= a {
+		n := &it.tree.nodes[ix]
+		if !it.overlap(data, int(n._left), a, b, yield) {
+			return false
+		}
+		if data.Start(ix) > b {
+			return true
+		}
+		if data.End(ix) >= a && !yield(ix) {
+			return false
+		}
+		ix = int(n._right)
+	}
+	return true
+}
+
+// update recalculates maximal end of subtree
+func (it *IntervalTree[T]) update(ix, l, r int) {
+	m := it.data.End(ix)
+	if l != null {
+		m = max(m, it.maxEnd[l])
+	}
+	if r != null {
+		m = max(m, it.maxEnd[r])
+	}
+	it.maxEnd[ix] = m
+}
+
+// intervalSwap moves maximal ends together with intervals
+type intervalSwap[T cmp.Ordered] struct {
+	IntervalData[T]
+	it *IntervalTree[T]
+}
+
+func (s intervalSwap[T]) Swap(i, j int) {
+	s.IntervalData.Swap(i, j)
+	s.it.maxEnd[i], s.it.maxEnd[j] = s.it.maxEnd[j], s.it.maxEnd[i]
+}
+
+// intervalRelocator forwards Relocate to data, which implements it
+type intervalRelocator[T cmp.Ordered] struct {
+	intervalSwap[T]
+	Relocator
+}
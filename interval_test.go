@@ -0,0 +1,120 @@
+package tree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type span struct {
+	from, to int
+}
+
+type spans []span
+
+func (s spans) Len() int           { return len(s) }
+func (s spans) Less(i, j int) bool { return s[i].from < s[j].from }
+func (s spans) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s spans) Start(i int) int    { return s[i].from }
+func (s spans) End(i int) int      { return s[i].to }
+
+func check_maxend(t *testing.T, data spans, it *IntervalTree[int], ix int) int {
+	n := &it.tree.nodes[ix]
+	m := data[ix].to
+	for _, ch := range [...]index{n._left, n._right} {
+		if ch != null {
+			m = max(m, check_maxend(t, data, it, int(ch)))
+		}
+	}
+	if it.maxEnd[ix] != m {
+		t.Fatalf("node %d: max end is %d, want %d", ix, it.maxEnd[ix], m)
+	}
+	return m
+}
+
+func Test_IntervalTree(t *testing.T) {
+	data := spans{}
+	it := &IntervalTree[int]{}
+	for i := 0; i < 1000; i++ {
+		if it.Len() > 0 && rand.Intn(3) == 0 {
+			it.Delete(data, rand.Intn(it.Len()))
+			data = data[:it.Len()]
+		} else {
+			from := rand.Intn(1000)
+			data = append(data, span{from, from + rand.Intn(100)})
+			it.Insert(data)
+		}
+		if it.Len() == 0 {
+			continue
+		}
+		check(t, data, &it.tree, it.tree.root)
+		check_maxend(t, data, it, it.tree.root)
+
+		a := rand.Intn(1100)
+		b := a + rand.Intn(50)
+		want := 0
+		for _, s := range data {
+			if s.from <= b && s.to >= a {
+				want++
+			}
+		}
+		got, prev := 0, -1
+		for ix := range it.Overlap(data, a, b) {
+			if data[ix].from > b || data[ix].to < a {
+				t.Fatalf("[%d, %d] doesn't overlap [%d, %d]", data[ix].from, data[ix].to, a, b)
+			}
+			if data[ix].from < prev {
+				t.Fatalf("intervals out of order")
+			}
+			prev = data[ix].from
+			got++
+		}
+		if got != want {
+			t.Fatalf("Overlap(%d, %d) found %d, want %d", a, b, got, want)
+		}
+		for ix := range it.Stab(data, a) {
+			if data[ix].from > a || data[ix].to < a {
+				t.Fatalf("[%d, %d] doesn't contain %d", data[ix].from, data[ix].to, a)
+			}
+			break
+		}
+	}
+}
+
+// relspans tracks positions of spans by id
+type relspans struct {
+	spans
+	id  []int
+	pos map[int]int
+}
+
+func (s relspans) Swap(i, j int) {
+	s.spans.Swap(i, j)
+	s.id[i], s.id[j] = s.id[j], s.id[i]
+}
+
+func (s relspans) Relocate(from, to int) {
+	s.pos[s.id[to]] = to
+}
+
+func Test_IntervalTreeRelocator(t *testing.T) {
+	data := relspans{pos: map[int]int{}}
+	it := &IntervalTree[int]{}
+	for i := 0; i < 100; i++ {
+		from := rand.Intn(1000)
+		data.spans = append(data.spans, span{from, from + rand.Intn(100)})
+		data.id = append(data.id, i)
+		data.pos[i] = i
+		it.Insert(data)
+	}
+	for it.Len() > 0 {
+		ix := rand.Intn(it.Len())
+		delete(data.pos, data.id[ix])
+		it.Delete(data, ix)
+		data.spans, data.id = data.spans[:it.Len()], data.id[:it.Len()]
+		for i, id := range data.id {
+			if data.pos[id] != i {
+				t.Fatalf("position of %d is %d, reported %d", id, i, data.pos[id])
+			}
+		}
+	}
+}
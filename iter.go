@@ -0,0 +1,70 @@
+package tree
+
+import "iter"
+
+// All returns iterator over indices of elements in order.
+// Tree should not be modified during iteration.
+//
+//     for ix := range index.All() {
+//         fmt.Printf("%d ", data[ix])
+//     }
+func (t *Tree) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for ix := t.Next(-1); ix < t.Len(); ix = t.Next(ix) {
+			if !yield(ix) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns iterator over indices of elements in reverse order.
+// Tree should not be modified during iteration.
+func (t *Tree) Backward() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for ix := t.Prev(t.Len()); ix >= 0; ix = t.Prev(ix) {
+			if !yield(ix) {
+				return
+			}
+		}
+	}
+}
+
+// Range returns iterator over indices of elements in order,
+// starting from first element for which from is true (as in Search)
+// and finishing with last element for which to is true (as in SearchLast).
+//
+//     for ix := range index.Range(
+//         func(i int) bool { return data[i] >= lo },
+//         func(i int) bool { return data[i] <= hi }) {
+//         ...
+//     }
+//
+// Tree should not be modified during iteration.
+func (t *Tree) Range(from, to func(i int) bool) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		first := t.Search(from)
+		if first == t.Len() || !to(first) {
+			return
+		}
+		last := t.SearchLast(to)
+		for ix := first; yield(ix) && ix != last; ix = t.Next(ix) {
+		}
+	}
+}
+
+// All returns iterator over elements in order.
+// Tree should not be modified during iteration.
+func (f *Func[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		f.Ascend(yield)
+	}
+}
+
+// Backward returns iterator over elements in reverse order.
+// Tree should not be modified during iteration.
+func (f *Func[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		f.Descend(yield)
+	}
+}
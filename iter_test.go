@@ -0,0 +1,75 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_Iter(t *testing.T) {
+	tree := Tree{}
+	for range tree.All() {
+		t.Fatalf("iteration over empty tree")
+	}
+	for range tree.Backward() {
+		t.Fatalf("iteration over empty tree")
+	}
+	data := sort.IntSlice{}
+	for i := 0; i < 200; i++ {
+		data = append(data, rand.Intn(100))
+		tree.Insert(data)
+	}
+	sorted := append(sort.IntSlice{}, data...)
+	sort.Sort(sorted)
+
+	k := 0
+	for ix := range tree.All() {
+		if data[ix] != sorted[k] {
+			t.Fatalf("All: %d != %d", data[ix], sorted[k])
+		}
+		k++
+	}
+	if k != len(sorted) {
+		t.Fatalf("All visited %d of %d", k, len(sorted))
+	}
+	for ix := range tree.Backward() {
+		k--
+		if data[ix] != sorted[k] {
+			t.Fatalf("Backward: %d != %d", data[ix], sorted[k])
+		}
+	}
+	if k != 0 {
+		t.Fatalf("Backward visited %d of %d", len(sorted)-k, len(sorted))
+	}
+
+	k = 0
+	for range tree.All() {
+		if k++; k == 10 {
+			break
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		lo, hi := rand.Intn(110)-5, rand.Intn(110)-5
+		want := []int{}
+		for _, v := range sorted {
+			if v >= lo && v <= hi {
+				want = append(want, v)
+			}
+		}
+		got := []int{}
+		for ix := range tree.Range(
+			func(i int) bool { return data[i] >= lo },
+			func(i int) bool { return data[i] <= hi }) {
+			got = append(got, data[ix])
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Range(%d, %d): %d elements, want %d", lo, hi, len(got), len(want))
+		}
+		for j := range got {
+			if got[j] != want[j] {
+				t.Fatalf("Range(%d, %d): %v, want %v", lo, hi, got, want)
+			}
+		}
+	}
+}
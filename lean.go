@@ -0,0 +1,406 @@
+package tree
+
+import (
+	"iter"
+	"sort"
+)
+
+// Lean is a balanced tree with the same indexing as Tree, but its
+// nodes keep no parent links, subtree sizes and cached min and max.
+// Node takes 12 bytes instead of 20 (24 instead of 40 with tree64).
+//
+// Insert and Delete go top-down from root remembering path on stack,
+// and iteration is done with Cursor, which keeps path to current
+// element. Since node can't be reached from its index alone, Delete
+// has to find element by comparisons with data: it takes O(log n + d)
+// where d is number of elements equal to deleted one.
+//
+// Zero value is an empty tree.
+type Lean struct {
+	root  int
+	nodes []lnode
+}
+
+type lnode struct {
+	_left  index
+	_right index
+	height int8
+}
+
+func (n *lnode) link(i direction) int {
+	if i == right {
+		return int(n._right)
+	}
+	return int(n._left)
+}
+
+func (n *lnode) set_link(i direction, ix int) {
+	if i == right {
+		n._right = index(ix)
+	} else {
+		n._left = index(ix)
+	}
+}
+
+// Len returns number of indexed elements
+func (l *Lean) Len() int {
+	return len(l.nodes)
+}
+
+// Min returns index of minimal element
+// panics if called on empty tree
+func (l *Lean) Min() int {
+	return l.edge(left)
+}
+
+// Max returns index of maximal element
+// panics if called on empty tree
+func (l *Lean) Max() int {
+	return l.edge(right)
+}
+
+// Search is the same as Tree.Search
+func (l *Lean) Search(pred func(i int) bool) int {
+	res := len(l.nodes)
+	for now := l.top(); now != null; {
+		if pred(now) {
+			res = now
+			now = int(l.nodes[now]._left)
+		} else {
+			now = int(l.nodes[now]._right)
+		}
+	}
+	return res
+}
+
+// SearchLast is the same as Tree.SearchLast
+func (l *Lean) SearchLast(pred func(i int) bool) int {
+	res := -1
+	for now := l.top(); now != null; {
+		if pred(now) {
+			res = now
+			now = int(l.nodes[now]._right)
+		} else {
+			now = int(l.nodes[now]._left)
+		}
+	}
+	return res
+}
+
+// Insert adds in-order element of sort.Interface at index Len(),
+// as Tree.Insert does.
+func (l *Lean) Insert(data sort.Interface) {
+	ix := len(l.nodes)
+	if ix == MaxSize {
+		panic(ErrCapacity)
+	}
+	l.nodes = append(l.nodes, lnode{null, null, 1})
+	if ix == 0 {
+		l.root = 0
+		return
+	}
+	var buf [48]index
+	path := buf[:0]
+	cur := l.root
+	var dir direction
+	for {
+		path = append(path, index(cur))
+		dir = direction(!data.Less(ix, cur))
+		next := l.nodes[cur].link(dir)
+		if next == null {
+			break
+		}
+		cur = next
+	}
+	l.nodes[cur].set_link(dir, ix)
+	l.rebalance(path)
+}
+
+// Delete removes element ix as Tree.Delete does: element from last
+// index is moved to ix, so removed element ends at index Len().
+// If data implements Relocator, it is notified about moved element.
+// data should be in the same order as when element was inserted.
+func (l *Lean) Delete(data sort.Interface, ix int) {
+	if ix < 0 || ix >= len(l.nodes) {
+		panic(ErrOutOfRange)
+	}
+	var buf [48]index
+	path, ok := l.locate(data, ix, l.root, buf[:0])
+	if !ok {
+		panic(ErrCorrupt)
+	}
+	l.detach(path)
+	last := len(l.nodes) - 1
+	if ix != last {
+		path, ok = l.locate(data, last, l.root, buf[:0])
+		if !ok {
+			panic(ErrCorrupt)
+		}
+		l.nodes[ix] = l.nodes[last]
+		l.relink(path, ix)
+		data.Swap(ix, last)
+		if rel, ok := data.(Relocator); ok {
+			rel.Relocate(last, ix)
+		}
+	}
+	l.nodes = l.nodes[:last]
+}
+
+// All returns iterator over indices of elements in order
+func (l *Lean) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for c := l.First(); c.Valid() && yield(c.Index()); c.Next() {
+		}
+	}
+}
+
+// Backward returns iterator over indices of elements in reverse order
+func (l *Lean) Backward() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for c := l.Last(); c.Valid() && yield(c.Index()); c.Prev() {
+		}
+	}
+}
+
+// Cursor points to an element of Lean and moves to its neighbours.
+// It remembers path from root, so any modification of tree
+// invalidates it.
+type Cursor struct {
+	l    *Lean
+	path []index
+}
+
+// First returns cursor at minimal element
+func (l *Lean) First() *Cursor {
+	c := &Cursor{l: l}
+	c.descend(l.top(), left)
+	return c
+}
+
+// Last returns cursor at maximal element
+func (l *Lean) Last() *Cursor {
+	c := &Cursor{l: l}
+	c.descend(l.top(), right)
+	return c
+}
+
+// Seek returns cursor at first in-order element for which predicate
+// is true (i.e. at Search(pred)). Cursor is not valid, if there is
+// no such element.
+func (l *Lean) Seek(pred func(i int) bool) *Cursor {
+	c := &Cursor{l: l}
+	k := 0
+	for now := l.top(); now != null; {
+		c.path = append(c.path, index(now))
+		if pred(now) {
+			k = len(c.path)
+			now = int(l.nodes[now]._left)
+		} else {
+			now = int(l.nodes[now]._right)
+		}
+	}
+	c.path = c.path[:k]
+	return c
+}
+
+// Valid reports if cursor points to element
+func (c *Cursor) Valid() bool {
+	return len(c.path) > 0
+}
+
+// Index returns index of current element
+// panics if cursor is not valid
+func (c *Cursor) Index() int {
+	if len(c.path) == 0 {
+		panic(ErrOutOfRange)
+	}
+	return int(c.path[len(c.path)-1])
+}
+
+// Next moves cursor to next element. Cursor becomes not valid
+// after maximal element.
+func (c *Cursor) Next() {
+	c.step(right)
+}
+
+// Prev moves cursor to previous element. Cursor becomes not valid
+// before minimal element.
+func (c *Cursor) Prev() {
+	c.step(left)
+}
+
+func (c *Cursor) step(dir direction) {
+	if len(c.path) == 0 {
+		return
+	}
+	nodes := c.l.nodes
+	if ch := nodes[c.path[len(c.path)-1]].link(dir); ch != null {
+		c.descend(ch, !dir)
+		return
+	}
+	for {
+		cur := int(c.path[len(c.path)-1])
+		c.path = c.path[:len(c.path)-1]
+		if len(c.path) == 0 || nodes[c.path[len(c.path)-1]].link(!dir) == cur {
+			return
+		}
+	}
+}
+
+// descend pushes ix and its descendants in direction dir
+func (c *Cursor) descend(ix int, dir direction) {
+	for ; ix != null; ix = c.l.nodes[ix].link(dir) {
+		c.path = append(c.path, index(ix))
+	}
+}
+
+// top returns root or null for empty tree
+func (l *Lean) top() int {
+	if len(l.nodes) == 0 {
+		return null
+	}
+	return l.root
+}
+
+// edge returns index of extreme element in direction dir
+func (l *Lean) edge(dir direction) int {
+	if len(l.nodes) == 0 {
+		panic(ErrEmpty)
+	}
+	ix := l.root
+	for l.nodes[ix].link(dir) != null {
+		ix = l.nodes[ix].link(dir)
+	}
+	return ix
+}
+
+// locate appends to path nodes from cur to ix.
+// Elements equal to ix are searched on both sides.
+func (l *Lean) locate(data sort.Interface, ix, cur int, path []index) ([]index, bool) {
+	for cur != null {
+		path = append(path, index(cur))
+		n := &l.nodes[cur]
+		switch {
+		case cur == ix:
+			return path, true
+		case data.Less(ix, cur):
+			cur = int(n._left)
+		case data.Less(cur, ix):
+			cur = int(n._right)
+		default:
+			if p, ok := l.locate(data, ix, int(n._left), path); ok {
+				return p, true
+			}
+			cur = int(n._right)
+		}
+	}
+	return path, false
+}
+
+// detach removes last node of path from tree structure
+func (l *Lean) detach(path []index) {
+	k := len(path) - 1
+	ix := int(path[k])
+	n := &l.nodes[ix]
+	if n._left != null && n._right != null {
+		/* successor takes place of removed node */
+		path = append(path, n._right)
+		for l.nodes[path[len(path)-1]]._left != null {
+			path = append(path, l.nodes[path[len(path)-1]]._left)
+		}
+		s := int(path[len(path)-1])
+		path = path[:len(path)-1]
+		if p := int(path[len(path)-1]); p == ix {
+			n._right = l.nodes[s]._right
+		} else {
+			l.nodes[p]._left = l.nodes[s]._right
+		}
+		l.nodes[s] = *n
+		l.relink(path[:k+1], s)
+		path[k] = index(s)
+	} else {
+		ch := int(n._left)
+		if ch == null {
+			ch = int(n._right)
+		}
+		l.relink(path, ch)
+		path = path[:k]
+	}
+	*n = lnode{null, null, 1}
+	l.rebalance(path)
+}
+
+// relink points parent of last node of path (or root) to ix
+func (l *Lean) relink(path []index, ix int) {
+	k := len(path) - 1
+	if k == 0 {
+		l.root = ix
+		return
+	}
+	p := &l.nodes[path[k-1]]
+	if p._left == path[k] {
+		p._left = index(ix)
+	} else {
+		p._right = index(ix)
+	}
+}
+
+// rebalance fixes nodes of path from bottom to root
+func (l *Lean) rebalance(path []index) {
+	for k := len(path) - 1; k >= 0; k-- {
+		ix := int(path[k])
+		h := l.nodes[ix].height
+		r := l.balance(ix)
+		if r != ix {
+			l.relink(path[:k+1], r)
+		} else if l.nodes[ix].height == h {
+			return
+		}
+	}
+}
+
+// balance restores balance of subtree ix and returns its new root
+func (l *Lean) balance(ix int) int {
+	n := &l.nodes[ix]
+	lh, rh := l.height(n._left), l.height(n._right)
+	var dir direction
+	if lh < rh-1 {
+		dir = right
+	} else if lh-1 > rh {
+		dir = left
+	} else {
+		l.fix(ix)
+		return ix
+	}
+	ch := n.link(dir)
+	chnode := &l.nodes[ch]
+	if l.height(index(chnode.link(dir))) < l.height(index(chnode.link(!dir))) {
+		/* rotate child */
+		n.set_link(dir, l.rotate(ch, !dir))
+	}
+	return l.rotate(ix, dir)
+}
+
+// rotate lifts child of ix in direction dir and returns it
+func (l *Lean) rotate(ix int, dir direction) int {
+	n := &l.nodes[ix]
+	ch := n.link(dir)
+	chnode := &l.nodes[ch]
+	n.set_link(dir, chnode.link(!dir))
+	chnode.set_link(!dir, ix)
+	l.fix(ix)
+	l.fix(ch)
+	return ch
+}
+
+func (l *Lean) fix(ix int) {
+	n := &l.nodes[ix]
+	n.height = max_i8(l.height(n._left), l.height(n._right)) + 1
+}
+
+func (l *Lean) height(ix index) int8 {
+	if ix == null {
+		return 0
+	}
+	return l.nodes[ix].height
+}
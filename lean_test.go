@@ -0,0 +1,161 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"unsafe"
+)
+
+// tree converts lean layout to Tree for verification
+func (l *Lean) tree() *Tree {
+	t := &Tree{root: l.top(), nodes: make([]node, len(l.nodes))}
+	for i, n := range l.nodes {
+		t.nodes[i] = node{_parent: null, _left: n._left, _right: n._right, height: n.height}
+	}
+	for i, n := range l.nodes {
+		if n._left != null {
+			t.nodes[n._left]._parent = index(i)
+		}
+		if n._right != null {
+			t.nodes[n._right]._parent = index(i)
+		}
+	}
+	t.setroot(t.root)
+	return t
+}
+
+func Test_Lean(t *testing.T) {
+	for k := 0; k < 50; k++ {
+		data := relslice{sort.IntSlice{}, map[int]int{}}
+		l := Lean{}
+		for i := 0; i < 500; i++ {
+			if l.Len() > 0 && rand.Intn(3) == 0 {
+				ix := rand.Intn(l.Len())
+				v := data.IntSlice[ix]
+				l.Delete(dupslice{data}, ix)
+				if data.IntSlice[l.Len()] != v {
+					t.Fatalf("Delete don't place value at last position")
+				}
+				delete(data.pos, v)
+				data.IntSlice = data.IntSlice[:l.Len()]
+			} else {
+				/* many duplicates to exercise locate */
+				v := rand.Intn(20)*1000 + i
+				data.IntSlice = append(data.IntSlice, v)
+				data.pos[v] = len(data.IntSlice) - 1
+				l.Insert(dupslice{data})
+			}
+			tr := l.tree()
+			if err := tr.validate(true); err != nil {
+				t.Fatal(err)
+			}
+			if err := tr.Verify(dupslice{data}); err != nil {
+				t.Fatal(err)
+			}
+			for i, v := range data.IntSlice {
+				if data.pos[v] != i {
+					t.Fatalf("position of %d is %d, reported %d", v, i, data.pos[v])
+				}
+			}
+		}
+		sorted := append([]int(nil), data.IntSlice...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i]/1000 < sorted[j]/1000 })
+		i := 0
+		for ix := range l.All() {
+			if data.IntSlice[ix]/1000 != sorted[i]/1000 {
+				t.Fatalf("All: %d at %d, want %d", data.IntSlice[ix], i, sorted[i])
+			}
+			i++
+		}
+		if i != l.Len() {
+			t.Fatalf("All visited %d of %d", i, l.Len())
+		}
+		for ix := range l.Backward() {
+			i--
+			if data.IntSlice[ix]/1000 != sorted[i]/1000 {
+				t.Fatalf("Backward: %d at %d, want %d", data.IntSlice[ix], i, sorted[i])
+			}
+		}
+		if l.Len() == 0 {
+			continue
+		}
+		v := rand.Intn(20)
+		ge := func(i int) bool { return data.IntSlice[i]/1000 >= v }
+		c := l.Seek(ge)
+		if ix := l.Search(ge); c.Valid() != (ix < l.Len()) || c.Valid() && c.Index() != ix {
+			t.Fatalf("Seek(%d) differs from Search", v)
+		}
+		if c.Valid() {
+			c.Prev()
+			if c.Valid() && ge(c.Index()) {
+				t.Fatalf("Seek(%d) isn't first", v)
+			}
+		}
+		if data.IntSlice[l.Min()]/1000 != sorted[0]/1000 ||
+			data.IntSlice[l.Max()]/1000 != sorted[len(sorted)-1]/1000 {
+			t.Fatalf("Min/Max mismatch")
+		}
+	}
+}
+
+// dupslice compares values of relslice by thousands,
+// so that there are many equal elements
+type dupslice struct {
+	relslice
+}
+
+func (d dupslice) Less(i, j int) bool {
+	return d.IntSlice[i]/1000 < d.IntSlice[j]/1000
+}
+
+func Test_LeanSize(t *testing.T) {
+	width := unsafe.Sizeof(index(0))
+	if s := unsafe.Sizeof(lnode{}); s != 3*width {
+		t.Fatalf("lean node takes %d bytes, want %d", s, 3*width)
+	}
+}
+
+func benchmark_Layout(b *testing.B, n int, lean bool) {
+	data := benchslice{}
+	for j := 0; j < n; j++ {
+		data = append(data, bigstruct{I: rand.Intn(1 << 30)})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := append(benchslice{}, data...)
+		if lean {
+			l := Lean{}
+			for j := 1; j <= n; j++ {
+				l.Insert(d[:j])
+			}
+			for c := l.First(); c.Valid(); c.Next() {
+			}
+			for l.Len() > n/2 {
+				l.Delete(d[:l.Len()], rand.Intn(l.Len()))
+			}
+		} else {
+			t := Tree{}
+			for j := 1; j <= n; j++ {
+				t.Insert(d[:j])
+			}
+			for range t.All() {
+			}
+			for t.Len() > n/2 {
+				t.Delete(d[:t.Len()], rand.Intn(t.Len()))
+			}
+		}
+	}
+	size := unsafe.Sizeof(node{})
+	if lean {
+		size = unsafe.Sizeof(lnode{})
+	}
+	b.ReportMetric(float64(size), "B/node")
+}
+
+func Benchmark_LayoutTree1000(b *testing.B)   { benchmark_Layout(b, 1000, false) }
+func Benchmark_LayoutTree10000(b *testing.B)  { benchmark_Layout(b, 10000, false) }
+func Benchmark_LayoutTree100000(b *testing.B) { benchmark_Layout(b, 100000, false) }
+func Benchmark_LayoutLean1000(b *testing.B)   { benchmark_Layout(b, 1000, true) }
+func Benchmark_LayoutLean10000(b *testing.B)  { benchmark_Layout(b, 10000, true) }
+func Benchmark_LayoutLean100000(b *testing.B) { benchmark_Layout(b, 100000, true) }
@@ -0,0 +1,212 @@
+package tree
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"unsafe"
+)
+
+// Binary format of Tree (all numbers are little-endian):
+//
+//     magic   [4]byte  "AVLT"
+//     version uint8    1
+//     width   uint8    size of link in bytes, 4 or 8
+//     len     int64    number of nodes
+//     root    int64
+//     min     int64
+//     max     int64
+//     nodes   [len]{parent, left, right [width]byte; height int8}
+//     crc     uint32   CRC-32 (IEEE) of all preceding bytes
+//
+// Subtree sizes are not stored, but recalculated on load. Links are
+// widened or narrowed on load, so that tree written with tree64 tag
+// could be read without it and vice versa.
+const (
+	marshalMagic   = "AVLT"
+	marshalVersion = 1
+	marshalHeader  = 4 + 1 + 1 + 4*8
+)
+
+const linkWidth = int(unsafe.Sizeof(index(0)))
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// Only tree structure is stored, data should be saved separately.
+func (t *Tree) MarshalBinary() ([]byte, error) {
+	n := len(t.nodes)
+	buf := make([]byte, 0, marshalHeader+n*(3*linkWidth+1)+4)
+	buf = append(buf, marshalMagic...)
+	buf = append(buf, marshalVersion, byte(linkWidth))
+	for _, v := range [...]int{n, t.root, t.min, t.max} {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+	}
+	for i := range t.nodes {
+		nd := &t.nodes[i]
+		for _, l := range [...]index{nd._parent, nd._left, nd._right} {
+			buf = appendLink(buf, l)
+		}
+		buf = append(buf, byte(nd.height))
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// Tree structure is validated, so that corrupted input
+// is rejected with error instead of breaking tree.
+// Tree is left unchanged on error.
+func (t *Tree) UnmarshalBinary(buf []byte) error {
+	if len(buf) < marshalHeader+4 {
+		return fmt.Errorf("%w: binary data is too short", ErrCorrupt)
+	}
+	if string(buf[:4]) != marshalMagic {
+		return fmt.Errorf("%w: wrong binary magic", ErrCorrupt)
+	}
+	if buf[4] != marshalVersion {
+		return fmt.Errorf("%w: unsupported binary version %d", ErrCorrupt, buf[4])
+	}
+	width := int(buf[5])
+	if width != 4 && width != 8 {
+		return fmt.Errorf("%w: unsupported link width %d", ErrCorrupt, width)
+	}
+	body, sum := buf[:len(buf)-4], binary.LittleEndian.Uint32(buf[len(buf)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return fmt.Errorf("%w: binary checksum mismatch", ErrCorrupt)
+	}
+	var hdr [4]int
+	for i := range hdr {
+		hdr[i] = int(int64(binary.LittleEndian.Uint64(buf[6+8*i:])))
+	}
+	n := hdr[0]
+	if n < 0 || n > MaxSize || len(body) != marshalHeader+n*(3*width+1) {
+		return fmt.Errorf("%w: binary length mismatch", ErrCorrupt)
+	}
+	nt := Tree{root: hdr[1], min: hdr[2], max: hdr[3], nodes: make([]node, n)}
+	p := body[marshalHeader:]
+	for i := range nt.nodes {
+		nd := &nt.nodes[i]
+		for _, l := range [...]*index{&nd._parent, &nd._left, &nd._right} {
+			var v int64
+			v, p = readLink(p, width)
+			if v < null || v >= int64(n) {
+				return fmt.Errorf("%w: node %d: link %d out of range", ErrCorrupt, i, v)
+			}
+			*l = index(v)
+		}
+		nd.height, p = int8(p[0]), p[1:]
+	}
+	if err := nt.validate(true); err != nil {
+		return err
+	}
+	nt.h, nt.aug = t.h, t.aug
+	if nt.h != nil {
+		nt.h.reset(n)
+	}
+	nt.augment(nt.root)
+	*t = nt
+	return nil
+}
+
+func appendLink(buf []byte, l index) []byte {
+	v := uint64(l)
+	for i := 0; i < linkWidth; i++ {
+		buf = append(buf, byte(v))
+		v >>= 8
+	}
+	return buf
+}
+
+// readLink reads sign-extended link of given width
+func readLink(p []byte, width int) (int64, []byte) {
+	var v uint64
+	for i := width - 1; i >= 0; i-- {
+		v = v<<8 | uint64(p[i])
+	}
+	shift := 64 - 8*width
+	return int64(v<<shift) >> shift, p[width:]
+}
+
+// validate checks links, heights and bookkeeping of tree structure.
+// If load is true, subtree sizes are recalculated instead of checked.
+func (t *Tree) validate(load bool) error {
+	n := len(t.nodes)
+	if n == 0 {
+		if load {
+			t.root, t.min, t.max = null, null, null
+		}
+		return nil
+	}
+	if t.root < 0 || t.root >= n {
+		return fmt.Errorf("%w: root %d out of range", ErrCorrupt, t.root)
+	}
+	if t.nodes[t.root]._parent != null {
+		return fmt.Errorf("%w: node %d: root has parent %d", ErrCorrupt, t.root, t.nodes[t.root]._parent)
+	}
+	cnt := 0
+	seen := make([]bool, n)
+	if _, err := t.validateNode(t.root, 0, &cnt, seen, load); err != nil {
+		return err
+	}
+	if cnt != n {
+		return fmt.Errorf("%w: %d of %d nodes are reachable from root", ErrCorrupt, cnt, n)
+	}
+	min, max := t.root, t.root
+	for t.nodes[min]._left != null {
+		min = int(t.nodes[min]._left)
+	}
+	for t.nodes[max]._right != null {
+		max = int(t.nodes[max]._right)
+	}
+	if t.min != min {
+		return fmt.Errorf("%w: min is %d, but leftmost node is %d", ErrCorrupt, t.min, min)
+	}
+	if t.max != max {
+		return fmt.Errorf("%w: max is %d, but rightmost node is %d", ErrCorrupt, t.max, max)
+	}
+	return nil
+}
+
+func (t *Tree) validateNode(ix, depth int, cnt *int, seen []bool, load bool) (int8, error) {
+	if depth > 127 {
+		return 0, fmt.Errorf("%w: node %d: tree is too deep", ErrCorrupt, ix)
+	}
+	if seen[ix] {
+		return 0, fmt.Errorf("%w: node %d is reachable twice", ErrCorrupt, ix)
+	}
+	seen[ix] = true
+	*cnt++
+	nd := &t.nodes[ix]
+	if nd._left != null && nd._left == nd._right {
+		return 0, fmt.Errorf("%w: node %d: both children are %d", ErrCorrupt, ix, nd._left)
+	}
+	var hs [2]int8
+	for i, ch := range [...]index{nd._left, nd._right} {
+		if ch == null {
+			continue
+		}
+		if ch < 0 || int(ch) >= len(t.nodes) {
+			return 0, fmt.Errorf("%w: node %d: child %d out of range", ErrCorrupt, ix, ch)
+		}
+		if int(t.nodes[ch]._parent) != ix {
+			return 0, fmt.Errorf("%w: node %d: child %d has parent %d", ErrCorrupt, ix, ch, t.nodes[ch]._parent)
+		}
+		h, err := t.validateNode(int(ch), depth+1, cnt, seen, load)
+		if err != nil {
+			return 0, err
+		}
+		hs[i] = h
+	}
+	if bal := hs[0] - hs[1]; bal < -1 || bal > 1 {
+		return 0, fmt.Errorf("%w: node %d: unbalanced, heights %d and %d", ErrCorrupt, ix, hs[0], hs[1])
+	}
+	if h := max_i8(hs[0], hs[1]) + 1; nd.height != h {
+		return 0, fmt.Errorf("%w: node %d: height is %d, want %d", ErrCorrupt, ix, nd.height, h)
+	}
+	size := t.size(nd._left) + t.size(nd._right) + 1
+	if load {
+		nd.size = size
+	} else if nd.size != size {
+		return 0, fmt.Errorf("%w: node %d: size is %d, want %d", ErrCorrupt, ix, nd.size, size)
+	}
+	return nd.height, nil
+}
@@ -0,0 +1,132 @@
+package tree
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_Marshal(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 10, 1000} {
+		data := sort.IntSlice{}
+		tree := Tree{}
+		for i := 0; i < n; i++ {
+			data = append(data, rand.Intn(100))
+			tree.Insert(data)
+		}
+		buf, err := tree.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded := Tree{}
+		if err := loaded.UnmarshalBinary(buf); err != nil {
+			t.Fatalf("UnmarshalBinary: %v", err)
+		}
+		if loaded.Len() != n {
+			t.Fatalf("loaded %d nodes, want %d", loaded.Len(), n)
+		}
+		check_tree(t, data, &loaded)
+		for i := 0; i < 10 && n > 0; i++ {
+			data = append(data, rand.Intn(100))
+			loaded.Insert(data)
+			loaded.Delete(data, rand.Intn(loaded.Len()))
+			data = data[:loaded.Len()]
+			check_tree(t, data, &loaded)
+		}
+	}
+}
+
+func Test_MarshalCorrupt(t *testing.T) {
+	data := sort.IntSlice{}
+	tree := Tree{}
+	for i := 0; i < 100; i++ {
+		data = append(data, rand.Intn(100))
+		tree.Insert(data)
+	}
+	buf, _ := tree.MarshalBinary()
+	for i := 0; i < 200; i++ {
+		bad := append([]byte{}, buf...)
+		bad[rand.Intn(len(bad))] ^= byte(1 + rand.Intn(255))
+		if err := (&Tree{}).UnmarshalBinary(bad); err == nil {
+			t.Fatalf("corrupted data is accepted")
+		}
+	}
+	if err := (&Tree{}).UnmarshalBinary(buf[:len(buf)-1]); err == nil {
+		t.Fatalf("truncated data is accepted")
+	}
+
+	/* valid checksum, broken structure */
+	broken := tree
+	broken.nodes = append([]node{}, tree.nodes...)
+	broken.nodes[broken.root].height++
+	bad, _ := broken.MarshalBinary()
+	loaded := Tree{}
+	if err := loaded.UnmarshalBinary(bad); err == nil {
+		t.Fatalf("broken tree is accepted")
+	}
+	if loaded.Len() != 0 {
+		t.Fatalf("tree is modified on error")
+	}
+
+	/* shared child hides unreachable node */
+	shared := Tree{root: 0, min: 1, max: 1, nodes: []node{
+		{_parent: null, _left: 1, _right: 1, height: 2},
+		{_parent: 0, _left: null, _right: null, height: 1},
+		{_parent: 5, _left: 7, _right: null, height: 9},
+	}}
+	bad, _ = shared.MarshalBinary()
+	if err := loaded.UnmarshalBinary(bad); err == nil {
+		t.Fatalf("tree with shared child is accepted")
+	}
+}
+
+// rewidth converts binary data of tree to other link width
+func rewidth(buf []byte, width int) []byte {
+	old := int(buf[5])
+	res := append([]byte{}, buf[:marshalHeader]...)
+	res[5] = byte(width)
+	for p := buf[marshalHeader : len(buf)-4]; len(p) > 0; {
+		for i := 0; i < 3; i++ {
+			var v int64
+			v, p = readLink(p, old)
+			for j := 0; j < width; j++ {
+				res = append(res, byte(v>>(8*j)))
+			}
+		}
+		res, p = append(res, p[0]), p[1:]
+	}
+	return binary.LittleEndian.AppendUint32(res, crc32.ChecksumIEEE(res))
+}
+
+func Test_MarshalWidth(t *testing.T) {
+	data := sort.IntSlice{}
+	tree := Tree{}
+	for i := 0; i < 100; i++ {
+		data = append(data, rand.Intn(100))
+		tree.Insert(data)
+	}
+	buf, _ := tree.MarshalBinary()
+	for _, width := range []int{4, 8} {
+		loaded := Tree{}
+		if err := loaded.UnmarshalBinary(rewidth(buf, width)); err != nil {
+			t.Fatalf("UnmarshalBinary with width %d: %v", width, err)
+		}
+		check_tree(t, data, &loaded)
+	}
+
+	/* link which doesn't fit in 4 bytes */
+	bad := rewidth(buf, 8)
+	bad[marshalHeader+4] = 1
+	binary.LittleEndian.PutUint32(bad[len(bad)-4:], crc32.ChecksumIEEE(bad[:len(bad)-4]))
+	if err := (&Tree{}).UnmarshalBinary(bad); err == nil {
+		t.Fatalf("wide link is accepted")
+	}
+	bad = rewidth(buf, 4)
+	bad[5] = 2
+	binary.LittleEndian.PutUint32(bad[len(bad)-4:], crc32.ChecksumIEEE(bad[:len(bad)-4]))
+	if err := (&Tree{}).UnmarshalBinary(bad); err == nil {
+		t.Fatalf("unsupported width is accepted")
+	}
+}
@@ -0,0 +1,86 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MultiIndex keeps several trees with different orderings over
+// the same data. Since Tree.Delete moves elements of data, separate
+// trees over shared data would be broken by deletion through any
+// of them; MultiIndex performs insertions and deletions so that
+// every tree stays consistent.
+//
+//     mi := tree.NewMultiIndex(2)
+//     rows = append(rows, row)
+//     mi.Insert(byID(rows), byTime(rows))
+//     ix := mi.Index(0).Search(func(i int) bool { return rows[i].ID >= id })
+//     mi.Delete(byID(rows), ix)
+//     rows = rows[:mi.Len()]
+type MultiIndex struct {
+	trees []Tree
+}
+
+// NewMultiIndex returns MultiIndex with n empty trees
+func NewMultiIndex(n int) *MultiIndex {
+	return &MultiIndex{trees: make([]Tree, n)}
+}
+
+// Len returns number of indexed elements
+func (m *MultiIndex) Len() int {
+	if len(m.trees) == 0 {
+		return 0
+	}
+	return m.trees[0].Len()
+}
+
+// Index returns k-th tree.
+// It should be used only for searching and iteration,
+// modifications should be done through MultiIndex.
+func (m *MultiIndex) Index(k int) *Tree {
+	return &m.trees[k]
+}
+
+// Insert adds element at index Len() to every tree.
+// orders[k] provides ordering for k-th tree.
+func (m *MultiIndex) Insert(orders ...sort.Interface) {
+	if len(orders) != len(m.trees) {
+		panic(fmt.Errorf("tree: MultiIndex.Insert got %d orderings for %d trees", len(orders), len(m.trees)))
+	}
+	for k := range m.trees {
+		m.trees[k].Insert(orders[k])
+	}
+}
+
+// Delete removes element ix from every tree.
+// Last element of data is moved to index ix, and deleted element is
+// moved to index Len(). Only data.Swap is used, so any of orderings
+// could be passed. If data implements Relocator, it is notified
+// about moved element.
+func (m *MultiIndex) Delete(data sort.Interface, ix int) {
+	last := m.Len() - 1
+	if ix < 0 || ix > last {
+		panic(ErrOutOfRange)
+	}
+	if ix != last {
+		data.Swap(ix, last)
+	}
+	for k := range m.trees {
+		t := &m.trees[k]
+		t.own()
+		if ix != last {
+			t.swapnodes(ix, last)
+			if t.h != nil {
+				t.h.swap(ix, last)
+			}
+		}
+		t.detach(last)
+		if t.h != nil {
+			t.h.remove()
+		}
+		t.nodes = t.nodes[:last]
+	}
+	if rel, ok := data.(Relocator); ok && ix != last {
+		rel.Relocate(last, ix)
+	}
+}
@@ -0,0 +1,49 @@
+package tree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type mrow struct {
+	id, ts int
+}
+
+type byID []mrow
+
+func (b byID) Len() int           { return len(b) }
+func (b byID) Less(i, j int) bool { return b[i].id < b[j].id }
+func (b byID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+type byTS []mrow
+
+func (b byTS) Len() int           { return len(b) }
+func (b byTS) Less(i, j int) bool { return b[i].ts < b[j].ts }
+func (b byTS) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+func Test_MultiIndex(t *testing.T) {
+	for k := 0; k < 50; k++ {
+		mi := NewMultiIndex(2)
+		rows := []mrow{}
+		for i := 0; i < 500; i++ {
+			if mi.Len() > 0 && rand.Intn(3) == 0 {
+				ix := rand.Intn(mi.Len())
+				v := rows[ix]
+				if k&1 == 0 {
+					mi.Delete(byID(rows), ix)
+				} else {
+					mi.Delete(byTS(rows), ix)
+				}
+				if rows[mi.Len()] != v {
+					t.Fatalf("Delete don't place value at last position")
+				}
+				rows = rows[:mi.Len()]
+			} else {
+				rows = append(rows, mrow{rand.Intn(100), rand.Intn(100)})
+				mi.Insert(byID(rows), byTS(rows))
+			}
+			check_tree(t, byID(rows), mi.Index(0))
+			check_tree(t, byTS(rows), mi.Index(1))
+		}
+	}
+}
@@ -1,9 +1,6 @@
 package tree
 
 type direction bool
-type index int32
-
-const MaxSize = (1 << 30) - 1
 
 const (
 	left  = direction(false)
@@ -15,6 +12,7 @@ type node struct {
 	_parent index
 	_left   index
 	_right  index
+	size    index
 	height  int8
 }
 
@@ -33,3 +31,16 @@ func (n *node) set_link(i direction, ix int) {
 		n._left = index(ix)
 	}
 }
+
+// shift moves all links of node by d
+func (n *node) shift(d index) {
+	if n._parent != null {
+		n._parent += d
+	}
+	if n._left != null {
+		n._left += d
+	}
+	if n._right != null {
+		n._right += d
+	}
+}
@@ -0,0 +1,48 @@
+package tree
+
+import "sort"
+
+// Relocator could be implemented by sort.Interface passed to
+// Tree.Delete, Tree.DeleteAndPrev, Tree.Split and other modifications
+// to be notified about elements which were moved to other index,
+// so that external references to elements (hash maps from key
+// to index, etc) could be fixed.
+type Relocator interface {
+	// Relocate is called when element at index from is moved to index to.
+	Relocate(from, to int)
+}
+
+// recorder remembers swaps done by single operation
+type recorder struct {
+	sort.Interface
+	swaps [][2]int
+}
+
+func (r *recorder) Swap(i, j int) {
+	r.Interface.Swap(i, j)
+	r.swaps = append(r.swaps, [2]int{i, j})
+}
+
+// report calls Relocate for every element, which changed its index,
+// except removed one, which ends at index removed.
+func (r *recorder) report(rel Relocator, removed int) {
+	var pos, was []int
+	at := func(p int) int {
+		for k, q := range pos {
+			if q == p {
+				return k
+			}
+		}
+		pos, was = append(pos, p), append(was, p)
+		return len(pos) - 1
+	}
+	for _, s := range r.swaps {
+		a, b := at(s[0]), at(s[1])
+		was[a], was[b] = was[b], was[a]
+	}
+	for k, p := range pos {
+		if p != removed && was[k] != p {
+			rel.Relocate(was[k], p)
+		}
+	}
+}
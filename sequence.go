@@ -0,0 +1,77 @@
+package tree
+
+import "iter"
+
+// Sequence is a list of elements ordered by their positions only,
+// without any comparison. Like Tree, it indexes elements of external
+// data, and every operation takes O(log n):
+//
+//     seq.InsertAt(2)                // new element is data[seq.Len()-1]
+//     data = append(data, v)
+//     seq.RemoveAt(0, func(i, j int) { data[i], data[j] = data[j], data[i] })
+//     data = data[:seq.Len()]
+//     v = data[seq.At(1)]
+//
+// Zero value is an empty sequence.
+type Sequence struct {
+	tree Tree
+}
+
+// Len returns number of elements
+func (s *Sequence) Len() int {
+	return s.tree.Len()
+}
+
+// InsertAt adds new element at position pos, shifting elements
+// at pos and after it. New element gets index Len()-1 in data,
+// which is returned.
+// panics if pos < 0 or pos > Len()
+func (s *Sequence) InsertAt(pos int) int {
+	if pos < 0 || pos > s.Len() {
+		panic(ErrOutOfRange)
+	}
+	s.tree.InsertBefore(s.tree.Select(pos))
+	return s.Len() - 1
+}
+
+// RemoveAt removes element at position pos.
+// As with Tree.Delete, elements of data are moved with swap, so that
+// removed element ends at index Len() and could be truncated.
+// panics if pos < 0 or pos >= Len()
+func (s *Sequence) RemoveAt(pos int, swap func(i, j int)) {
+	if pos < 0 || pos >= s.Len() {
+		panic(ErrOutOfRange)
+	}
+	s.tree.Delete(swapFunc(swap), s.tree.Select(pos))
+}
+
+// At returns index in data of element at position pos.
+// panics if pos < 0 or pos >= Len()
+func (s *Sequence) At(pos int) int {
+	if pos < 0 || pos >= s.Len() {
+		panic(ErrOutOfRange)
+	}
+	return s.tree.Select(pos)
+}
+
+// IndexOf returns position of element with index ix in data.
+// panics if ix < 0 or ix >= Len()
+func (s *Sequence) IndexOf(ix int) int {
+	if ix < 0 || ix >= s.Len() {
+		panic(ErrOutOfRange)
+	}
+	return s.tree.Rank(ix)
+}
+
+// All returns iterator over indices of elements in sequence order
+func (s *Sequence) All() iter.Seq[int] {
+	return s.tree.All()
+}
+
+// swapFunc is sort.Interface for Tree.Delete, which only swaps
+// elements and never compares them
+type swapFunc func(i, j int)
+
+func (f swapFunc) Len() int           { panic("tree: swapFunc.Len") }
+func (f swapFunc) Less(i, j int) bool { panic("tree: swapFunc.Less") }
+func (f swapFunc) Swap(i, j int)      { f(i, j) }
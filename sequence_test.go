@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// seqslice orders values by their positions in reference list
+type seqslice struct {
+	data []int
+	pos  map[int]int
+}
+
+func (s seqslice) Len() int           { return len(s.data) }
+func (s seqslice) Less(i, j int) bool { return s.pos[s.data[i]] < s.pos[s.data[j]] }
+func (s seqslice) Swap(i, j int)      { s.data[i], s.data[j] = s.data[j], s.data[i] }
+
+func Test_Sequence(t *testing.T) {
+	seq := Sequence{}
+	data := []int{}
+	ref := []int{}
+	swap := func(i, j int) { data[i], data[j] = data[j], data[i] }
+	for i := 0; i < 2000; i++ {
+		if len(ref) > 0 && rand.Intn(3) == 0 {
+			pos := rand.Intn(len(ref))
+			seq.RemoveAt(pos, swap)
+			if data[seq.Len()] != ref[pos] {
+				t.Fatalf("RemoveAt(%d) left %d at the end, want %d", pos, data[seq.Len()], ref[pos])
+			}
+			data = data[:seq.Len()]
+			ref = append(ref[:pos], ref[pos+1:]...)
+		} else {
+			pos := rand.Intn(len(ref) + 1)
+			if ix := seq.InsertAt(pos); ix != len(data) {
+				t.Fatalf("InsertAt returns %d, want %d", ix, len(data))
+			}
+			data = append(data, i)
+			ref = append(ref[:pos], append([]int{i}, ref[pos:]...)...)
+		}
+		if seq.Len() != len(ref) {
+			t.Fatalf("Len() = %d, want %d", seq.Len(), len(ref))
+		}
+		pos := make(map[int]int, len(ref))
+		for p, v := range ref {
+			pos[v] = p
+		}
+		if err := seq.tree.Verify(seqslice{data, pos}); err != nil {
+			t.Fatal(err)
+		}
+		for k := 0; k < 5 && len(ref) > 0; k++ {
+			pos := rand.Intn(len(ref))
+			ix := seq.At(pos)
+			if data[ix] != ref[pos] {
+				t.Fatalf("At(%d) is %d, want %d", pos, data[ix], ref[pos])
+			}
+			if seq.IndexOf(ix) != pos {
+				t.Fatalf("IndexOf(%d) = %d, want %d", ix, seq.IndexOf(ix), pos)
+			}
+		}
+	}
+	pos := 0
+	for ix := range seq.All() {
+		if data[ix] != ref[pos] {
+			t.Fatalf("All: %d at %d, want %d", data[ix], pos, ref[pos])
+		}
+		pos++
+	}
+}
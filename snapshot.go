@@ -0,0 +1,90 @@
+package tree
+
+import "iter"
+
+// Snapshot is a read-only view of a Tree at the moment of
+// Tree.Snapshot call. It shares nodes with the tree, and tree copies
+// them on first modification after snapshot was taken.
+//
+// Snapshot indexes elements by their positions in data at the moment
+// of snapshot. Since Delete moves elements of data, readers of snapshot
+// should use data which is not modified by writer (for example, copy of
+// data or append-only data, when only Insert is used).
+type Snapshot struct {
+	t Tree
+}
+
+// Snapshot returns read-only view of current state of tree.
+// It takes O(1), and first modification of tree after it takes O(n).
+func (t *Tree) Snapshot() *Snapshot {
+	t.shared = true
+	return &Snapshot{t.view()}
+}
+
+// view returns copy of tree sharing nodes with it
+func (t *Tree) view() Tree {
+	n := len(t.nodes)
+	return Tree{
+		root:   t.root,
+		min:    t.min,
+		max:    t.max,
+		nodes:  t.nodes[:n:n],
+		shared: true,
+	}
+}
+
+// own copies nodes shared with snapshot before modification
+func (t *Tree) own() {
+	if t.shared {
+		t.nodes = append([]node(nil), t.nodes...)
+		t.shared = false
+	}
+}
+
+// Len returns number of indexed elements
+func (s *Snapshot) Len() int {
+	return s.t.Len()
+}
+
+// Min returns index of minimum element
+// panics if called on empty snapshot
+func (s *Snapshot) Min() int {
+	return s.t.Min()
+}
+
+// Max returns index of maximum element
+// panics if called on empty snapshot
+func (s *Snapshot) Max() int {
+	return s.t.Max()
+}
+
+// Search returns first index for which predicate is true
+// returns Len() if no element satisfies predicate
+func (s *Snapshot) Search(pred func(i int) bool) int {
+	return s.t.Search(pred)
+}
+
+// SearchLast returns last index for which predicate is true
+// returns -1 if no element satisfies predicate
+func (s *Snapshot) SearchLast(pred func(i int) bool) int {
+	return s.t.SearchLast(pred)
+}
+
+// Next returns index of next in-order element.
+// if argument is -1, then return index of minimal element.
+// returns Len() on finish.
+func (s *Snapshot) Next(i int) int {
+	return s.t.Next(i)
+}
+
+// Prev returns index of previos in-order element.
+// if argument is Len(), then return index of maximal element.
+// returns -1 on finish.
+func (s *Snapshot) Prev(i int) int {
+	return s.t.Prev(i)
+}
+
+// All returns iterator over indices of elements in order.
+func (s *Snapshot) All() iter.Seq[int] {
+	return s.t.All()
+}
@@ -0,0 +1,51 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_Snapshot(t *testing.T) {
+	data := sort.IntSlice{}
+	tree := Tree{}
+	for i := 0; i < 100; i++ {
+		data = append(data, rand.Intn(100))
+		tree.Insert(data)
+	}
+	type version struct {
+		snap *Snapshot
+		data sort.IntSlice
+	}
+	versions := []version{}
+	for i := 0; i < 200; i++ {
+		if rand.Intn(10) == 0 {
+			versions = append(versions, version{
+				tree.Snapshot(),
+				append(sort.IntSlice{}, data...)})
+		}
+		if rand.Intn(2) == 0 {
+			tree.Delete(data, rand.Intn(tree.Len()))
+			data = data[:tree.Len()]
+		} else {
+			data = append(data, rand.Intn(100))
+			tree.Insert(data)
+		}
+		check_tree(t, data, &tree)
+	}
+	for _, v := range versions {
+		check_tree(t, v.data, &v.snap.t)
+		if v.snap.Len() != len(v.data) {
+			t.Fatalf("snapshot length changed")
+		}
+		x := rand.Intn(100)
+		ix := v.snap.Search(func(i int) bool { return v.data[i] >= x })
+		jx := v.snap.SearchLast(func(i int) bool { return v.data[i] < x })
+		if ix < v.snap.Len() && v.data[ix] < x || v.snap.Prev(ix) != jx {
+			t.Fatalf("snapshot search failed")
+		}
+		if v.data[v.snap.Min()] > v.data[v.snap.Max()] {
+			t.Fatalf("snapshot min > max")
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package tree
+
+import (
+	"cmp"
+	"iter"
+)
+
+// SortedMap is an ordered map with unique keys.
+// It should be created with NewSortedMap or NewSortedMapFunc.
+type SortedMap[K, V any] struct {
+	f   Func[entry[K, V]]
+	cmp func(a, b K) int
+}
+
+type entry[K, V any] struct {
+	k K
+	v V
+}
+
+// NewSortedMap returns empty map with naturally ordered keys
+func NewSortedMap[K cmp.Ordered, V any]() *SortedMap[K, V] {
+	return NewSortedMapFunc[K, V](cmp.Compare[K])
+}
+
+// NewSortedMapFunc returns empty map with keys ordered by cmp
+// (as in NewFunc)
+func NewSortedMapFunc[K, V any](cmp func(a, b K) int) *SortedMap[K, V] {
+	m := &SortedMap[K, V]{cmp: cmp}
+	m.f.cmp = func(a, b entry[K, V]) int {
+		return cmp(a.k, b.k)
+	}
+	return m
+}
+
+// Len returns number of keys
+func (m *SortedMap[K, V]) Len() int {
+	return m.f.Len()
+}
+
+// Get returns value stored for key
+func (m *SortedMap[K, V]) Get(k K) (V, bool) {
+	ix := m.ceiling(k)
+	if ix == m.f.Len() || m.cmp(m.f.items[ix].k, k) != 0 {
+		var zero V
+		return zero, false
+	}
+	return m.f.items[ix].v, true
+}
+
+// Put stores value for key, replacing previous one.
+func (m *SortedMap[K, V]) Put(k K, v V) {
+	ix := m.ceiling(k)
+	if ix < m.f.Len() && m.cmp(m.f.items[ix].k, k) == 0 {
+		m.f.items[ix].v = v
+		return
+	}
+	m.f.Insert(entry[K, V]{k, v})
+}
+
+// Delete removes key from map.
+// Returns false if there were no such key.
+func (m *SortedMap[K, V]) Delete(k K) bool {
+	return m.f.Delete(entry[K, V]{k: k})
+}
+
+// Floor returns greatest key less than or equal to k
+func (m *SortedMap[K, V]) Floor(k K) (K, V, bool) {
+	return m.at(m.f.tree.Floor(m.key(k)))
+}
+
+// Ceiling returns least key greater than or equal to k
+func (m *SortedMap[K, V]) Ceiling(k K) (K, V, bool) {
+	return m.at(m.ceiling(k))
+}
+
+// Min returns least key
+func (m *SortedMap[K, V]) Min() (K, V, bool) {
+	if m.f.Len() == 0 {
+		return m.at(-1)
+	}
+	return m.at(m.f.tree.Min())
+}
+
+// Max returns greatest key
+func (m *SortedMap[K, V]) Max() (K, V, bool) {
+	if m.f.Len() == 0 {
+		return m.at(-1)
+	}
+	return m.at(m.f.tree.Max())
+}
+
+// All returns iterator over keys and values in order of keys.
+// Map should not be modified during iteration.
+func (m *SortedMap[K, V]) All() iter.Seq2[K, V] {
+	return m.seq(m.f.tree.All())
+}
+
+// Backward returns iterator over keys and values in reverse order of keys.
+// Map should not be modified during iteration.
+func (m *SortedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return m.seq(m.f.tree.Backward())
+}
+
+// Range returns iterator over keys in closed range [lo, hi] and their
+// values in order of keys.
+// Map should not be modified during iteration.
+func (m *SortedMap[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return m.seq(m.f.tree.Range(
+		func(i int) bool { return m.cmp(m.f.items[i].k, lo) >= 0 },
+		func(i int) bool { return m.cmp(m.f.items[i].k, hi) <= 0 }))
+}
+
+func (m *SortedMap[K, V]) ceiling(k K) int {
+	return m.f.tree.Ceiling(m.key(k))
+}
+
+// key returns comparator of elements with key k
+func (m *SortedMap[K, V]) key(k K) func(i int) int {
+	return func(i int) int {
+		return m.cmp(m.f.items[i].k, k)
+	}
+}
+
+func (m *SortedMap[K, V]) at(ix int) (K, V, bool) {
+	if ix < 0 || ix >= m.f.Len() {
+		var e entry[K, V]
+		return e.k, e.v, false
+	}
+	e := &m.f.items[ix]
+	return e.k, e.v, true
+}
+
+func (m *SortedMap[K, V]) seq(ixs iter.Seq[int]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for ix := range ixs {
+			e := &m.f.items[ix]
+			if !yield(e.k, e.v) {
+				return
+			}
+		}
+	}
+}
+
+// SortedSet is an ordered set.
+// It should be created with NewSortedSet or NewSortedSetFunc.
+type SortedSet[K any] struct {
+	m *SortedMap[K, struct{}]
+}
+
+// NewSortedSet returns empty set of naturally ordered keys
+func NewSortedSet[K cmp.Ordered]() *SortedSet[K] {
+	return &SortedSet[K]{NewSortedMap[K, struct{}]()}
+}
+
+// NewSortedSetFunc returns empty set of keys ordered by cmp
+// (as in NewFunc)
+func NewSortedSetFunc[K any](cmp func(a, b K) int) *SortedSet[K] {
+	return &SortedSet[K]{NewSortedMapFunc[K, struct{}](cmp)}
+}
+
+// Len returns number of keys
+func (s *SortedSet[K]) Len() int {
+	return s.m.Len()
+}
+
+// Has reports whether key is in set
+func (s *SortedSet[K]) Has(k K) bool {
+	_, ok := s.m.Get(k)
+	return ok
+}
+
+// Add adds key to set.
+// Returns false if key were already in set.
+func (s *SortedSet[K]) Add(k K) bool {
+	if s.Has(k) {
+		return false
+	}
+	s.m.f.Insert(entry[K, struct{}]{k: k})
+	return true
+}
+
+// Delete removes key from set.
+// Returns false if there were no such key.
+func (s *SortedSet[K]) Delete(k K) bool {
+	return s.m.Delete(k)
+}
+
+// Floor returns greatest key less than or equal to k
+func (s *SortedSet[K]) Floor(k K) (K, bool) {
+	k, _, ok := s.m.Floor(k)
+	return k, ok
+}
+
+// Ceiling returns least key greater than or equal to k
+func (s *SortedSet[K]) Ceiling(k K) (K, bool) {
+	k, _, ok := s.m.Ceiling(k)
+	return k, ok
+}
+
+// All returns iterator over keys in order.
+// Set should not be modified during iteration.
+func (s *SortedSet[K]) All() iter.Seq[K] {
+	return keys(s.m.All())
+}
+
+// Backward returns iterator over keys in reverse order.
+// Set should not be modified during iteration.
+func (s *SortedSet[K]) Backward() iter.Seq[K] {
+	return keys(s.m.Backward())
+}
+
+// Range returns iterator over keys in closed range [lo, hi] in order.
+// Set should not be modified during iteration.
+func (s *SortedSet[K]) Range(lo, hi K) iter.Seq[K] {
+	return keys(s.m.Range(lo, hi))
+}
+
+func keys[K any](seq iter.Seq2[K, struct{}]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range seq {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_SortedMap(t *testing.T) {
+	m := NewSortedMap[int, int]()
+	ref := map[int]int{}
+	for i := 0; i < 2000; i++ {
+		k := rand.Intn(300)
+		switch rand.Intn(3) {
+		case 0:
+			_, has := ref[k]
+			if m.Delete(k) != has {
+				t.Fatalf("Delete(%d) != %v", k, has)
+			}
+			delete(ref, k)
+		default:
+			m.Put(k, i)
+			ref[k] = i
+		}
+		if m.Len() != len(ref) {
+			t.Fatalf("Len() = %d, want %d", m.Len(), len(ref))
+		}
+		k = rand.Intn(310) - 5
+		v, ok := m.Get(k)
+		if rv, rok := ref[k]; ok != rok || v != rv {
+			t.Fatalf("Get(%d) = %d, %v, want %d, %v", k, v, ok, rv, rok)
+		}
+		fk, _, fok := m.Floor(k)
+		ck, _, cok := m.Ceiling(k)
+		wf, wc := -1, -1
+		for rk := range ref {
+			if rk <= k && rk > wf {
+				wf = rk
+			}
+			if rk >= k && (wc == -1 || rk < wc) {
+				wc = rk
+			}
+		}
+		if fok != (wf >= 0) || fok && fk != wf {
+			t.Fatalf("Floor(%d) = %d, %v, want %d", k, fk, fok, wf)
+		}
+		if cok != (wc >= 0) || cok && ck != wc {
+			t.Fatalf("Ceiling(%d) = %d, %v, want %d", k, ck, cok, wc)
+		}
+	}
+	keys := []int{}
+	for k := range ref {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	j := 0
+	for k, v := range m.All() {
+		if k != keys[j] || v != ref[k] {
+			t.Fatalf("All: %d=%d, want %d=%d", k, v, keys[j], ref[keys[j]])
+		}
+		j++
+	}
+	for k := range m.Backward() {
+		j--
+		if k != keys[j] {
+			t.Fatalf("Backward: %d, want %d", k, keys[j])
+		}
+	}
+	lo, hi := 100, 200
+	want := 0
+	for _, k := range keys {
+		if k >= lo && k <= hi {
+			want++
+		}
+	}
+	got := 0
+	for k := range m.Range(lo, hi) {
+		if k < lo || k > hi {
+			t.Fatalf("Range: %d out of [%d, %d]", k, lo, hi)
+		}
+		got++
+	}
+	if got != want {
+		t.Fatalf("Range visited %d, want %d", got, want)
+	}
+	if k, _, _ := m.Min(); k != keys[0] {
+		t.Fatalf("Min() = %d, want %d", k, keys[0])
+	}
+	if k, _, _ := m.Max(); k != keys[len(keys)-1] {
+		t.Fatalf("Max() = %d, want %d", k, keys[len(keys)-1])
+	}
+}
+
+func Test_SortedSet(t *testing.T) {
+	s := NewSortedSet[string]()
+	for _, k := range []string{"b", "a", "c", "a", "b"} {
+		s.Add(k)
+	}
+	if s.Len() != 3 || s.Add("a") || !s.Has("c") || s.Has("d") {
+		t.Fatalf("set is broken")
+	}
+	got := ""
+	for k := range s.All() {
+		got += k
+	}
+	if got != "abc" {
+		t.Fatalf("All() = %q", got)
+	}
+	if k, ok := s.Floor("bb"); !ok || k != "b" {
+		t.Fatalf("Floor(bb) = %q, %v", k, ok)
+	}
+	if k, ok := s.Ceiling("bb"); !ok || k != "c" {
+		t.Fatalf("Ceiling(bb) = %q, %v", k, ok)
+	}
+	if !s.Delete("b") || s.Delete("b") || s.Len() != 2 {
+		t.Fatalf("Delete is broken")
+	}
+	got = ""
+	for k := range s.Range("a", "b") {
+		got += k
+	}
+	if got != "a" {
+		t.Fatalf("Range(a, b) = %q", got)
+	}
+}
@@ -0,0 +1,151 @@
+package tree
+
+import (
+	"iter"
+	"sort"
+)
+
+// Sparse indexes arbitrary subset of elements of sort.Interface.
+// Unlike Tree, its nodes are mapped to data indices through indirection
+// table, so that element at any index could be inserted and removed,
+// and data is never reordered (Swap is not called).
+//
+// All indices accepted and returned by Sparse are indices in data.
+type Sparse struct {
+	tree Tree
+	ix   []int   // data index by node
+	node []index // node by data index, null if element is not indexed
+}
+
+// Len returns number of indexed elements
+func (s *Sparse) Len() int {
+	return s.tree.Len()
+}
+
+// Has reports whether element i is indexed
+func (s *Sparse) Has(i int) bool {
+	return i >= 0 && i < len(s.node) && s.node[i] != null
+}
+
+// Insert adds element i of data to index.
+// Returns false if element is already indexed.
+func (s *Sparse) Insert(data sort.Interface, i int) bool {
+	if i < 0 || i >= data.Len() {
+		panic(ErrOutOfRange)
+	}
+	if s.Has(i) {
+		return false
+	}
+	for len(s.node) <= i {
+		s.node = append(s.node, null)
+	}
+	s.node[i] = index(len(s.ix))
+	s.ix = append(s.ix, i)
+	s.tree.Insert(sparseData{s, data})
+	return true
+}
+
+// Remove deletes element i from index.
+// Returns false if element is not indexed.
+func (s *Sparse) Remove(i int) bool {
+	if !s.Has(i) {
+		return false
+	}
+	s.tree.Delete(sparseData{s, nil}, int(s.node[i]))
+	s.node[i] = null
+	s.ix = s.ix[:len(s.ix)-1]
+	return true
+}
+
+// Fix restores order after key of indexed element i was changed.
+func (s *Sparse) Fix(data sort.Interface, i int) {
+	if !s.Has(i) {
+		panic(ErrOutOfRange)
+	}
+	s.tree.Fix(sparseData{s, data}, int(s.node[i]))
+}
+
+// Min returns index of minimum element
+// panics if called on empty index
+func (s *Sparse) Min() int {
+	return s.ix[s.tree.Min()]
+}
+
+// Max returns index of maximum element
+// panics if called on empty index
+func (s *Sparse) Max() int {
+	return s.ix[s.tree.Max()]
+}
+
+// Search returns index of first element for which predicate is true
+// returns -1 if no element satisfies predicate
+func (s *Sparse) Search(pred func(i int) bool) int {
+	n := s.tree.Search(func(n int) bool { return pred(s.ix[n]) })
+	if n == s.tree.Len() {
+		return -1
+	}
+	return s.ix[n]
+}
+
+// SearchLast returns index of last element for which predicate is true
+// returns -1 if no element satisfies predicate
+func (s *Sparse) SearchLast(pred func(i int) bool) int {
+	n := s.tree.SearchLast(func(n int) bool { return pred(s.ix[n]) })
+	if n == -1 {
+		return -1
+	}
+	return s.ix[n]
+}
+
+// All returns iterator over indices of elements in order.
+// Index should not be modified during iteration.
+func (s *Sparse) All() iter.Seq[int] {
+	return s.seq(s.tree.All())
+}
+
+// Backward returns iterator over indices of elements in reverse order.
+// Index should not be modified during iteration.
+func (s *Sparse) Backward() iter.Seq[int] {
+	return s.seq(s.tree.Backward())
+}
+
+// Range returns iterator over indices of elements in order,
+// from first element for which from is true to last element
+// for which to is true (as in Tree.Range).
+// Index should not be modified during iteration.
+func (s *Sparse) Range(from, to func(i int) bool) iter.Seq[int] {
+	return s.seq(s.tree.Range(
+		func(n int) bool { return from(s.ix[n]) },
+		func(n int) bool { return to(s.ix[n]) }))
+}
+
+func (s *Sparse) seq(nodes iter.Seq[int]) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for n := range nodes {
+			if !yield(s.ix[n]) {
+				return
+			}
+		}
+	}
+}
+
+// sparseData maps nodes of Sparse to elements of data.
+// Swap moves nodes only, data is left untouched.
+type sparseData struct {
+	s    *Sparse
+	data sort.Interface
+}
+
+func (d sparseData) Len() int {
+	return len(d.s.ix)
+}
+
+func (d sparseData) Less(i, j int) bool {
+	return d.data.Less(d.s.ix[i], d.s.ix[j])
+}
+
+func (d sparseData) Swap(i, j int) {
+	ix := d.s.ix
+	ix[i], ix[j] = ix[j], ix[i]
+	d.s.node[ix[i]], d.s.node[ix[j]] = index(i), index(j)
+}
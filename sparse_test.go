@@ -0,0 +1,70 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_Sparse(t *testing.T) {
+	data := make(sort.IntSlice, 300)
+	for i := range data {
+		data[i] = rand.Intn(100)
+	}
+	orig := append(sort.IntSlice{}, data...)
+	s := Sparse{}
+	in := map[int]bool{}
+	for k := 0; k < 2000; k++ {
+		i := rand.Intn(len(data))
+		if rand.Intn(2) == 0 {
+			if s.Insert(data, i) == in[i] {
+				t.Fatalf("Insert(%d) with Has = %v", i, in[i])
+			}
+			in[i] = true
+		} else {
+			if s.Remove(i) != in[i] {
+				t.Fatalf("Remove(%d) with Has = %v", i, in[i])
+			}
+			delete(in, i)
+		}
+		if s.Len() != len(in) {
+			t.Fatalf("Len() = %d, want %d", s.Len(), len(in))
+		}
+		prev, cnt := -1, 0
+		for i := range s.All() {
+			if !in[i] || !s.Has(i) {
+				t.Fatalf("%d is not indexed", i)
+			}
+			if prev >= 0 && data[i] < data[prev] {
+				t.Fatalf("%d < %d", data[i], data[prev])
+			}
+			prev = i
+			cnt++
+		}
+		if cnt != len(in) {
+			t.Fatalf("iterated %d of %d", cnt, len(in))
+		}
+		if s.Len() > 0 {
+			check(t, sparseData{&s, data}, &s.tree, s.tree.root)
+			v := rand.Intn(100)
+			ix := s.Search(func(i int) bool { return data[i] >= v })
+			jx := s.SearchLast(func(i int) bool { return data[i] < v })
+			if ix >= 0 && data[ix] < v || jx >= 0 && data[jx] >= v {
+				t.Fatalf("search failed")
+			}
+			if data[s.Min()] > data[s.Max()] {
+				t.Fatalf("min > max")
+			}
+		}
+	}
+	for i := range data {
+		if data[i] != orig[i] {
+			t.Fatalf("data is reordered")
+		}
+	}
+	for i := range in {
+		data[i] = rand.Intn(100)
+		s.Fix(data, i)
+		check(t, sparseData{&s, data}, &s.tree, s.tree.root)
+	}
+}
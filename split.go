@@ -0,0 +1,297 @@
+package tree
+
+import "sort"
+
+// Split cuts tree at the boundary of predicate, which should be false
+// for some prefix of elements and true for the rest (as in Search).
+// Elements for which predicate is false are left in t, others are moved
+// to returned tree.
+//
+// Since index of element is its position in data, data is partitioned
+// to match: after Split elements of t occupy data[:t.Len()], and
+// elements of returned tree occupy data[t.Len():] and are indexed
+// relative to t.Len().
+//
+//     right := index.Split(data, func(i int) bool { return data[i] >= v })
+//     data, rdata := data[:index.Len()], data[index.Len():]
+//
+// If data implements Relocator, it is notified about every moved
+// element with indices of whole data, before it is cut at t.Len().
+//
+// If handles are enabled, elements left in t keep their handles, and
+// returned tree has handles enabled with new handles for its elements.
+// Returned tree has no augmentation.
+//
+// Rebalancing takes O(log n), but data is partitioned and nodes
+// of returned tree are copied in O(n).
+func (t *Tree) Split(data sort.Interface, pred func(i int) bool) *Tree {
+	if len(t.nodes) == 0 {
+		return &Tree{}
+	}
+	t.own()
+	l, r := t.split(t.root, pred)
+	k := int(t.size(index(l)))
+
+	/* move misplaced elements to their halves */
+	var lo, hi []int
+	t.walk(l, func(ix int) {
+		if ix >= k {
+			hi = append(hi, ix)
+		}
+	})
+	t.walk(r, func(ix int) {
+		if ix < k {
+			lo = append(lo, ix)
+		}
+	})
+	rel, _ := data.(Relocator)
+	for i, a := range hi {
+		b := lo[i]
+		t.swap(data, a, b)
+		t.swapnodes(a, b)
+		if rel != nil {
+			rel.Relocate(a, b)
+			rel.Relocate(b, a)
+		}
+		if l == a {
+			l = b
+		}
+		if r == b {
+			r = a
+		}
+	}
+
+	rt := &Tree{nodes: make([]node, len(t.nodes)-k)}
+	for i := range rt.nodes {
+		rt.nodes[i] = t.nodes[k+i]
+		rt.nodes[i].shift(index(-k))
+	}
+	if r != null {
+		r -= k
+	}
+	rt.setroot(r)
+	if t.h != nil {
+		for i := len(t.nodes); i > k; i-- {
+			t.h.remove()
+		}
+		rt.EnableHandles()
+	}
+	t.nodes = t.nodes[:k]
+	t.setroot(l)
+	return rt
+}
+
+// Join appends all elements of other to t.
+// Elements of other should already be placed in data right after
+// elements of t (i.e. at positions starting from t.Len()) and should be
+// not less than any element of t. other becomes empty.
+//
+//     data = append(data, rdata...)
+//     index.Join(right)
+//
+// If handles are enabled in t, appended elements get new handles.
+// If t has augmentation, all aggregates are recalculated.
+//
+// Rebalancing takes O(log n), but nodes of other are copied in O(n).
+func (t *Tree) Join(other *Tree) {
+	k := len(t.nodes)
+	if len(other.nodes) == 0 {
+		return
+	}
+	if k+len(other.nodes) > MaxSize {
+		panic(ErrCapacity)
+	}
+	if k == 0 {
+		h, aug := t.h, t.aug
+		*t, *other = *other, Tree{}
+		t.h, t.aug = h, aug
+		if h != nil {
+			h.reset(len(t.nodes))
+		}
+		t.augment(t.root)
+		return
+	}
+	t.own()
+	l, min := t.root, t.min
+	for _, n := range other.nodes {
+		n.shift(index(k))
+		if t.h != nil {
+			t.h.add(len(t.nodes))
+		}
+		t.nodes = append(t.nodes, n)
+	}
+	max := other.max + k
+	m, r := t.removeMin(other.root + k)
+	t.root = t.join(l, m, r)
+	t.min, t.max = min, max
+	t.augment(t.root)
+	*other = Tree{}
+}
+
+// split splits subtree ix into two detached subtrees
+// with elements for which predicate is false and true
+func (t *Tree) split(ix int, pred func(i int) bool) (l, r int) {
+	if ix == null {
+		return null, null
+	}
+	n := &t.nodes[ix]
+	a, b := int(n._left), int(n._right)
+	n._parent, n._left, n._right = null, null, null
+	if a != null {
+		t.nodes[a]._parent = null
+	}
+	if b != null {
+		t.nodes[b]._parent = null
+	}
+	if pred(ix) {
+		l, r = t.split(a, pred)
+		return l, t.join(r, ix, b)
+	}
+	l, r = t.split(b, pred)
+	return t.join(a, ix, l), r
+}
+
+// join makes balanced tree from detached subtrees l and r and single
+// detached node m, so that elements of l precede m and m precedes
+// elements of r. Returns root of joined tree.
+func (t *Tree) join(l, m, r int) int {
+	lh, rh := t.height(index(l)), t.height(index(r))
+	if lh > rh+1 {
+		p := l
+		for t.height(t.nodes[p]._right) > rh+1 {
+			p = int(t.nodes[p]._right)
+		}
+		t.link3(m, int(t.nodes[p]._right), r)
+		t.nodes[m]._parent = index(p)
+		t.nodes[p]._right = index(m)
+		t.balance(m)
+		return t.top(m)
+	}
+	if rh > lh+1 {
+		p := r
+		for t.height(t.nodes[p]._left) > lh+1 {
+			p = int(t.nodes[p]._left)
+		}
+		t.link3(m, l, int(t.nodes[p]._left))
+		t.nodes[m]._parent = index(p)
+		t.nodes[p]._left = index(m)
+		t.balance(m)
+		return t.top(m)
+	}
+	t.link3(m, l, r)
+	t.nodes[m]._parent = null
+	return m
+}
+
+// removeMin detaches minimal node of detached subtree r.
+// Returns detached node and root of the rest of subtree.
+func (t *Tree) removeMin(r int) (m, rest int) {
+	m = r
+	for t.nodes[m]._left != null {
+		m = int(t.nodes[m]._left)
+	}
+	n := &t.nodes[m]
+	p, c := int(n._parent), int(n._right)
+	n._parent, n._right = null, null
+	n.height, n.size = 1, 1
+	if c != null {
+		t.nodes[c]._parent = index(p)
+	}
+	if p == null {
+		return m, c
+	}
+	t.nodes[p]._left = index(c)
+	t.balance(p)
+	return m, t.top(p)
+}
+
+// link3 makes l and r children of m
+func (t *Tree) link3(m, l, r int) {
+	n := &t.nodes[m]
+	n._left, n._right = index(l), index(r)
+	if l != null {
+		t.nodes[l]._parent = index(m)
+	}
+	if r != null {
+		t.nodes[r]._parent = index(m)
+	}
+	t.fix(m)
+}
+
+// top returns root of subtree containing ix
+func (t *Tree) top(ix int) int {
+	for t.nodes[ix]._parent != null {
+		ix = int(t.nodes[ix]._parent)
+	}
+	return ix
+}
+
+// setroot sets root of a tree and finds its min and max
+func (t *Tree) setroot(r int) {
+	t.root, t.min, t.max = r, r, r
+	if r == null {
+		return
+	}
+	for t.nodes[t.min]._left != null {
+		t.min = int(t.nodes[t.min]._left)
+	}
+	for t.nodes[t.max]._right != null {
+		t.max = int(t.nodes[t.max]._right)
+	}
+}
+
+// walk calls fn for every node of subtree ix
+func (t *Tree) walk(ix int, fn func(ix int)) {
+	if ix == null {
+		return
+	}
+	n := &t.nodes[ix]
+	t.walk(int(n._left), fn)
+	fn(ix)
+	t.walk(int(n._right), fn)
+}
+
+// swapnodes exchanges positions of nodes i and j in t.nodes
+// preserving tree structure. It accounts for root, min and max of t,
+// but not for roots of other trees sharing nodes.
+func (t *Tree) swapnodes(i, j int) {
+	if i == j {
+		return
+	}
+	remap := func(x index) index {
+		switch int(x) {
+		case i:
+			return index(j)
+		case j:
+			return index(i)
+		}
+		return x
+	}
+	ni, nj := t.nodes[i], t.nodes[j]
+	var near [6]index
+	cnt := 0
+	for _, x := range [...]index{ni._parent, ni._left, ni._right,
+		nj._parent, nj._left, nj._right} {
+		if x == null || int(x) == i || int(x) == j {
+			continue
+		}
+		dup := false
+		for _, y := range near[:cnt] {
+			dup = dup || x == y
+		}
+		if !dup {
+			near[cnt] = x
+			cnt++
+		}
+	}
+	for _, x := range near[:cnt] {
+		n := &t.nodes[x]
+		n._parent, n._left, n._right = remap(n._parent), remap(n._left), remap(n._right)
+	}
+	ni._parent, ni._left, ni._right = remap(ni._parent), remap(ni._left), remap(ni._right)
+	nj._parent, nj._left, nj._right = remap(nj._parent), remap(nj._left), remap(nj._right)
+	t.nodes[i], t.nodes[j] = nj, ni
+	t.root = int(remap(index(t.root)))
+	t.min = int(remap(index(t.min)))
+	t.max = int(remap(index(t.max)))
+}
@@ -0,0 +1,107 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func check_tree(t *testing.T, data sort.Interface, tree *Tree) {
+	if tree.Len() == 0 {
+		return
+	}
+	if tree.nodes[tree.root]._parent != null {
+		t.Fatalf("root has parent")
+	}
+	check(t, data, tree, tree.root)
+	check_iter(t, data, tree)
+	check_rank(t, tree)
+	if tree.Max() != tree.Prev(tree.Len()) {
+		t.Fatalf("max is wrong")
+	}
+}
+
+func Test_Split(t *testing.T) {
+	for k := 0; k < 300; k++ {
+		n := rand.Intn(200)
+		data := sort.IntSlice{}
+		tree := Tree{}
+		for i := 0; i < n; i++ {
+			data = append(data, rand.Intn(100))
+			tree.Insert(data)
+		}
+		v := rand.Intn(110) - 5
+		cnt := 0
+		for _, x := range data {
+			if x < v {
+				cnt++
+			}
+		}
+		right := tree.Split(data, func(i int) bool { return data[i] >= v })
+		if tree.Len() != cnt || right.Len() != n-cnt {
+			t.Fatalf("Split sizes %d/%d, want %d/%d", tree.Len(), right.Len(), cnt, n-cnt)
+		}
+		ldata, rdata := data[:tree.Len()], data[tree.Len():]
+		for _, x := range ldata {
+			if x >= v {
+				t.Fatalf("%d left of %d", x, v)
+			}
+		}
+		for _, x := range rdata {
+			if x < v {
+				t.Fatalf("%d right of %d", x, v)
+			}
+		}
+		check_tree(t, ldata, &tree)
+		check_tree(t, rdata, right)
+
+		tree.Join(right)
+		if tree.Len() != n || right.Len() != 0 {
+			t.Fatalf("Join sizes %d/%d", tree.Len(), right.Len())
+		}
+		check_tree(t, data, &tree)
+	}
+}
+
+func Test_Join(t *testing.T) {
+	for k := 0; k < 300; k++ {
+		ldata, rdata := sort.IntSlice{}, sort.IntSlice{}
+		ltree, rtree := Tree{}, Tree{}
+		for i := rand.Intn(300); i > 0; i-- {
+			ldata = append(ldata, rand.Intn(100))
+			ltree.Insert(ldata)
+		}
+		for i := rand.Intn(300); i > 0; i-- {
+			rdata = append(rdata, 100+rand.Intn(100))
+			rtree.Insert(rdata)
+		}
+		data := append(ldata, rdata...)
+		ltree.Join(&rtree)
+		check_tree(t, data, &ltree)
+		for i := 0; i < 20 && ltree.Len() > 0; i++ {
+			data = append(data, rand.Intn(200))
+			ltree.Insert(data)
+			ltree.Delete(data, rand.Intn(ltree.Len()))
+			data = data[:ltree.Len()]
+			check_tree(t, data, &ltree)
+		}
+	}
+}
+
+func Test_SplitRelocator(t *testing.T) {
+	for k := 0; k < 100; k++ {
+		n := rand.Intn(200)
+		data := relslice{sort.IntSlice(rand.Perm(n)), map[int]int{}}
+		tree := Tree{}
+		for i, v := range data.IntSlice {
+			data.pos[v] = i
+			tree.Insert(data)
+		}
+		tree.Split(data, func(i int) bool { return data.IntSlice[i] >= n/2 })
+		for i, v := range data.IntSlice {
+			if data.pos[v] != i {
+				t.Fatalf("position of %d is %d, reported %d", v, i, data.pos[v])
+			}
+		}
+	}
+}
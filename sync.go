@@ -0,0 +1,201 @@
+package tree
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// SyncTree is a Func tree protected by readers/writer lock,
+// so that it could be used from several goroutines.
+//
+// Searches are done under read lock, modifications under write lock.
+// Iterators work on snapshot of the tree and don't hold lock while
+// calling loop body, so that it could call any method of SyncTree.
+//
+// Indices returned by SyncTree are valid only until next modification,
+// so that methods taking index (Get, InsertBefore and Delete) are safe
+// only with single writer: other writer could move element away between
+// calls. With several writers, use DeleteValue or DeleteFirst, which find
+// and remove element under one write lock.
+type SyncTree[T any] struct {
+	mu     sync.RWMutex
+	f      Func[T]
+	shared atomic.Bool // items and nodes are used by iterators
+}
+
+// NewSyncTree returns empty tree ordered by cmp (as in NewFunc)
+func NewSyncTree[T any](cmp func(a, b T) int) *SyncTree[T] {
+	return &SyncTree[T]{f: Func[T]{cmp: cmp}}
+}
+
+// Len returns number of elements
+func (s *SyncTree[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.f.Len()
+}
+
+// Get returns element at index ix
+func (s *SyncTree[T]) Get(ix int) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if ix < 0 || ix >= len(s.f.items) {
+		var zero T
+		return zero, false
+	}
+	return s.f.items[ix], true
+}
+
+// Min returns minimal element
+func (s *SyncTree[T]) Min() (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.f.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return s.f.Min(), true
+}
+
+// Max returns maximal element
+func (s *SyncTree[T]) Max() (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.f.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return s.f.Max(), true
+}
+
+// Search returns index and value of first in-order element for which
+// predicate is true (as in Func.Search).
+// Predicate is called under read lock and should not call SyncTree methods.
+func (s *SyncTree[T]) Search(pred func(v T) bool) (int, T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ix := s.f.tree.Search(func(i int) bool {
+		return pred(s.f.items[i])
+	})
+	if ix == len(s.f.items) {
+		var zero T
+		return ix, zero, false
+	}
+	return ix, s.f.items[ix], true
+}
+
+// Insert adds value to a tree and returns its index
+func (s *SyncTree[T]) Insert(v T) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.own()
+	s.f.Insert(v)
+	return len(s.f.items) - 1
+}
+
+// InsertBefore adds value in front of element at index cur,
+// or after maximal element if cur is Len().
+// It trusts you and doesn't check order.
+// It is safe only with single writer (see SyncTree).
+func (s *SyncTree[T]) InsertBefore(cur int, v T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur < 0 || cur > len(s.f.items) {
+		panic(ErrOutOfRange)
+	}
+	s.own()
+	s.f.items = append(s.f.items, v)
+	s.f.tree.InsertBefore(cur)
+}
+
+// Delete removes element at index ix and returns it.
+// Returns false if there is no such element.
+// It is safe only with single writer (see SyncTree).
+func (s *SyncTree[T]) Delete(ix int) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(ix)
+}
+
+// DeleteValue removes first element equal to v.
+// Returns false if there is no such element.
+func (s *SyncTree[T]) DeleteValue(v T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.delete(s.f.find(v))
+	return ok
+}
+
+// DeleteFirst removes first in-order element for which predicate is true
+// (as in Search) and returns it. Returns false if there is no such element.
+// Predicate is called under write lock and should not call SyncTree methods.
+func (s *SyncTree[T]) DeleteFirst(pred func(v T) bool) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(s.f.tree.Search(func(i int) bool {
+		return pred(s.f.items[i])
+	}))
+}
+
+// delete removes element ix under write lock
+func (s *SyncTree[T]) delete(ix int) (T, bool) {
+	var zero T
+	if ix < 0 || ix >= len(s.f.items) {
+		return zero, false
+	}
+	s.own()
+	f := &s.f
+	f.tree.Delete(funcSlice[T]{f}, ix)
+	v := f.items[len(f.items)-1]
+	f.items[len(f.items)-1] = zero
+	f.items = f.items[:len(f.items)-1]
+	return v, true
+}
+
+// All returns iterator over indices and elements in order.
+// It iterates over snapshot taken at the start of iteration.
+func (s *SyncTree[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		t, items := s.snapshot()
+		for ix := t.Next(-1); ix < t.Len(); ix = t.Next(ix) {
+			if !yield(ix, items[ix]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns iterator over indices and elements in reverse order.
+// It iterates over snapshot taken at the start of iteration.
+func (s *SyncTree[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		t, items := s.snapshot()
+		for ix := t.Prev(t.Len()); ix >= 0; ix = t.Prev(ix) {
+			if !yield(ix, items[ix]) {
+				return
+			}
+		}
+	}
+}
+
+// snapshot returns view of tree and items which will be copied
+// by writer before modification
+func (s *SyncTree[T]) snapshot() (*Tree, []T) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.shared.Store(true)
+	t := s.f.tree.view()
+	n := len(s.f.items)
+	return &t, s.f.items[:n:n]
+}
+
+// own copies items and nodes used by iterators.
+// Should be called under write lock.
+func (s *SyncTree[T]) own() {
+	if s.shared.Load() {
+		s.f.items = append([]T(nil), s.f.items...)
+		s.f.tree.shared = true
+		s.shared.Store(false)
+	}
+}
@@ -0,0 +1,107 @@
+package tree
+
+import (
+	"cmp"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func Test_SyncTree(t *testing.T) {
+	s := NewSyncTree(cmp.Compare[int])
+	var wg sync.WaitGroup
+	left := make([][]int, 4)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			/* every writer deletes only values it has inserted */
+			var mine []int
+			for i := 0; i < 500; i++ {
+				if n := len(mine); n > 10 && rand.Intn(3) == 0 {
+					k := rand.Intn(n)
+					v := mine[k]
+					mine = append(mine[:k], mine[k+1:]...)
+					if rand.Intn(2) == 0 {
+						if !s.DeleteValue(v) {
+							t.Errorf("DeleteValue(%d) failed", v)
+						}
+					} else if d, ok := s.DeleteFirst(func(x int) bool { return x >= v }); !ok || d != v {
+						t.Errorf("DeleteFirst(>= %d) removed %d", v, d)
+					}
+				} else {
+					v := rand.Intn(1000)*4 + w
+					mine = append(mine, v)
+					s.Insert(v)
+				}
+			}
+			left[w] = mine
+		}()
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				prev := -1
+				for _, v := range s.All() {
+					if v < prev {
+						t.Errorf("iteration out of order: %d < %d", v, prev)
+						return
+					}
+					prev = v
+				}
+				prev = 4000
+				for _, v := range s.Backward() {
+					if v > prev {
+						t.Errorf("backward iteration out of order: %d > %d", v, prev)
+						return
+					}
+					prev = v
+				}
+				x := rand.Intn(4000)
+				if _, v, ok := s.Search(func(v int) bool { return v >= x }); ok && v < x {
+					t.Errorf("search failed: %d < %d", v, x)
+				}
+				s.Min()
+				s.Max()
+			}
+		}()
+	}
+	wg.Wait()
+	n := 0
+	for _, mine := range left {
+		n += len(mine)
+	}
+	if s.Len() != n {
+		t.Fatalf("Len() = %d, want %d", s.Len(), n)
+	}
+}
+
+func Test_SyncTreeModifyInLoop(t *testing.T) {
+	s := NewSyncTree(cmp.Compare[int])
+	for i := 0; i < 100; i++ {
+		s.Insert(i)
+	}
+	cnt := 0
+	for _, v := range s.All() {
+		if v%2 == 0 {
+			s.Insert(v + 1000)
+		}
+		s.Delete(0)
+		cnt++
+	}
+	if cnt != 100 {
+		t.Fatalf("iterated over %d of 100 elements", cnt)
+	}
+	if s.Len() != 50 {
+		t.Fatalf("Len() = %d, want 50", s.Len())
+	}
+	s.InsertBefore(s.Len(), 5000)
+	if v, _ := s.Max(); v != 5000 {
+		t.Fatalf("InsertBefore(Len()) didn't append maximum")
+	}
+	if v, ok := s.Get(s.Len() - 1); !ok || v != 5000 {
+		t.Fatalf("Get of inserted element failed")
+	}
+}
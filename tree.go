@@ -14,8 +14,8 @@
 //     })
 //     fmt.Println("First greater or equal: data[%d] = %d > %d", ix, data[ix], v)
 //
-//     fmt.Println("Min:", data[tree.Min()], " Max:", data[tree.Max()])
-//     for ix := index.Next(-1); ix < index.Len(); ix = index.Next(ix) {
+//     fmt.Println("Min:", data[index.Min()], " Max:", data[index.Max()])
+//     for ix := range index.All() {
 //         fmt.Printf("%d ", data[ix])
 //     }
 //
@@ -25,7 +25,14 @@
 //     index.LeaveSorted(data)
 //     tree.StableSort(data)
 //
-// Limitation: index is limited to int32, so that maximum size is 2**31-1
+// Limitation: links between nodes are int32, so that maximum size
+// is MaxSize = 2**30-1. Build with tag tree64 to use int64 links
+// for larger trees:
+//
+//     go build -tags tree64
+//
+// It doubles memory used by tree: node takes 40 bytes instead of 20
+// (and handles take 16 bytes per element instead of 8).
 package tree
 
 import "sort"
@@ -35,6 +42,9 @@ import "sort"
 type Tree struct {
 	root, min, max int
 	nodes          []node
+	h              *handles
+	shared         bool // nodes are shared with Snapshot
+	aug            func(ix, left, right int)
 }
 
 // Len returns number of indexed elements
@@ -45,19 +55,31 @@ func (t *Tree) Len() int {
 // Min returns index of minimum element
 // panics if called on empty tree
 func (t *Tree) Min() int {
+	return must(t.TryMin())
+}
+
+// TryMin returns index of minimum element
+// returns ErrEmpty if called on empty tree
+func (t *Tree) TryMin() (int, error) {
 	if len(t.nodes) == 0 {
-		panic("Tree.Min should not be called on empty tree")
+		return 0, ErrEmpty
 	}
-	return t.min
+	return t.min, nil
 }
 
 // Max returns index of maximum element
 // panics if called on empty tree
 func (t *Tree) Max() int {
+	return must(t.TryMax())
+}
+
+// TryMax returns index of maximum element
+// returns ErrEmpty if called on empty tree
+func (t *Tree) TryMax() (int, error) {
 	if len(t.nodes) == 0 {
-		panic("Tree.Max should not be called on empty tree")
+		return 0, ErrEmpty
 	}
-	return t.max
+	return t.max, nil
 }
 
 // Search returns first index for which predicate is true
@@ -88,7 +110,7 @@ func (t *Tree) Search(pred func(i int) bool) int {
 // returns -1 if no element satisfies predicate
 func (t *Tree) SearchLast(pred func(i int) bool) int {
 	if len(t.nodes) == 0 {
-		return 0
+		return -1
 	}
 	now := t.root
 	last_true := -1
@@ -108,18 +130,71 @@ func (t *Tree) SearchLast(pred func(i int) bool) int {
 	}
 }
 
+// Find returns index of first element equal to the key,
+// or Len() if there is no such element.
+// cmp should compare element i with the key, returning negative number
+// if element is less than key, positive if it is greater, and zero
+// if they are equal.
+func (t *Tree) Find(cmp func(i int) int) int {
+	ix := t.Ceiling(cmp)
+	if ix < len(t.nodes) && cmp(ix) != 0 {
+		return len(t.nodes)
+	}
+	return ix
+}
+
+// Floor returns index of last element less than or equal to the key,
+// or -1 if there is no such element. cmp is the same as in Find.
+func (t *Tree) Floor(cmp func(i int) int) int {
+	return t.SearchLast(func(i int) bool { return cmp(i) <= 0 })
+}
+
+// Ceiling returns index of first element greater than or equal to the key,
+// or Len() if there is no such element. cmp is the same as in Find.
+func (t *Tree) Ceiling(cmp func(i int) int) int {
+	return t.Search(func(i int) bool { return cmp(i) >= 0 })
+}
+
+// Lower returns index of last element less than the key,
+// or -1 if there is no such element. cmp is the same as in Find.
+func (t *Tree) Lower(cmp func(i int) int) int {
+	return t.SearchLast(func(i int) bool { return cmp(i) < 0 })
+}
+
+// Higher returns index of first element greater than the key,
+// or Len() if there is no such element. cmp is the same as in Find.
+func (t *Tree) Higher(cmp func(i int) int) int {
+	return t.Search(func(i int) bool { return cmp(i) > 0 })
+}
+
+// EqualRange returns indices of first and last elements equal to the key,
+// or Len() and -1 if there is no such element. cmp is the same as in Find.
+func (t *Tree) EqualRange(cmp func(i int) int) (first, last int) {
+	first = t.Find(cmp)
+	if first == len(t.nodes) {
+		return first, -1
+	}
+	return first, t.Floor(cmp)
+}
+
 // Next returns index of next in-order element.
 // if argument is -1, then return index of minimal element.
 // returns t.Len() on finish.
 func (t *Tree) Next(i int) int {
-	if i > len(t.nodes) {
-		panic("Tree index overflow")
+	return must(t.TryNext(i))
+}
+
+// TryNext is like Next, but returns ErrOutOfRange on index overflow
+// and ErrCorrupt if tree links are broken.
+func (t *Tree) TryNext(i int) (int, error) {
+	if i < -1 || i > len(t.nodes) {
+		return 0, ErrOutOfRange
 	}
-	if i == len(t.nodes) || i == t.max {
-		return len(t.nodes)
+	if i == len(t.nodes) || i == t.max || len(t.nodes) == 0 {
+		return len(t.nodes), nil
 	}
 	if i == -1 {
-		return t.min
+		return t.min, nil
 	}
 	node := &t.nodes[i]
 	if node._right != null {
@@ -127,31 +202,39 @@ func (t *Tree) Next(i int) int {
 		for t.nodes[i]._left != null {
 			i = int(t.nodes[i]._left)
 		}
-		return i
+		return i, nil
 	}
 	for node._parent != null {
 		pix := int(node._parent)
-		dir := t.dir(i, pix)
-		if dir == left {
-			return pix
+		parent := &t.nodes[pix]
+		if int(parent._left) == i {
+			return pix, nil
+		} else if int(parent._right) != i {
+			return 0, ErrCorrupt
 		}
-		i, node = pix, &t.nodes[pix]
+		i, node = pix, parent
 	}
-	panic("tree broken")
+	return 0, ErrCorrupt
 }
 
 // Prev returns index of previos in-order element.
 // if argument is Tree.Len(), then return index of maximal element.
 // returns -1 on finish.
 func (t *Tree) Prev(i int) int {
-	if i > len(t.nodes) {
-		panic("Tree index overflow")
+	return must(t.TryPrev(i))
+}
+
+// TryPrev is like Prev, but returns ErrOutOfRange on index overflow
+// and ErrCorrupt if tree links are broken.
+func (t *Tree) TryPrev(i int) (int, error) {
+	if i < -1 || i > len(t.nodes) {
+		return 0, ErrOutOfRange
 	}
-	if i == -1 || i == t.min {
-		return -1
+	if i == -1 || i == t.min || len(t.nodes) == 0 {
+		return -1, nil
 	}
 	if i == len(t.nodes) {
-		return t.max
+		return t.max, nil
 	}
 	node := &t.nodes[i]
 	if node._left != null {
@@ -159,32 +242,209 @@ func (t *Tree) Prev(i int) int {
 		for t.nodes[i]._right != null {
 			i = int(t.nodes[i]._right)
 		}
-		return i
+		return i, nil
 	}
 	for node._parent != null {
 		pix := int(node._parent)
-		dir := t.dir(i, pix)
-		if dir == right {
-			return pix
+		parent := &t.nodes[pix]
+		if int(parent._right) == i {
+			return pix, nil
+		} else if int(parent._left) != i {
+			return 0, ErrCorrupt
+		}
+		i, node = pix, parent
+	}
+	return 0, ErrCorrupt
+}
+
+// Rank returns number of elements preceding element ix in order,
+// so that Select(Rank(ix)) == ix.
+// Rank(-1) returns -1 and Rank(Len()) returns Len().
+func (t *Tree) Rank(ix int) int {
+	if ix < -1 || ix > len(t.nodes) {
+		panic(ErrOutOfRange)
+	}
+	if ix == -1 || ix == len(t.nodes) {
+		return ix
+	}
+	node := &t.nodes[ix]
+	rank := int(t.size(node._left))
+	for node._parent != null {
+		pix := int(node._parent)
+		if t.dir(ix, pix) == right {
+			rank += int(t.size(t.nodes[pix]._left)) + 1
+		}
+		ix, node = pix, &t.nodes[pix]
+	}
+	return rank
+}
+
+// Select returns index of k-th element in order (counting from 0).
+// returns -1 if k < 0 and Len() if k >= Len().
+func (t *Tree) Select(k int) int {
+	if k < 0 {
+		return -1
+	}
+	if k >= len(t.nodes) {
+		return len(t.nodes)
+	}
+	now := t.root
+	for {
+		node := &t.nodes[now]
+		ls := int(t.size(node._left))
+		if k < ls {
+			now = int(node._left)
+		} else if k == ls {
+			return now
+		} else {
+			k -= ls + 1
+			now = int(node._right)
+		}
+	}
+}
+
+// Compare returns -1 if element i precedes element j in order,
+// 1 if it follows j, and 0 if i == j.
+// It uses only tree structure and doesn't compare elements,
+// so it works for trees built with InsertBefore.
+// panics if i or j is out of range
+func (t *Tree) Compare(i, j int) int {
+	if i < 0 || i >= len(t.nodes) || j < 0 || j >= len(t.nodes) {
+		panic(ErrOutOfRange)
+	}
+	if i == j {
+		return 0
+	}
+	di, dj := t.depth(i), t.depth(j)
+	a, b := i, j
+	ca, cb := null, null /* children of a and b we came from */
+	for ; di > dj; di-- {
+		ca, a = a, int(t.nodes[a]._parent)
+	}
+	for ; dj > di; dj-- {
+		cb, b = b, int(t.nodes[b]._parent)
+	}
+	for a != b {
+		ca, a = a, int(t.nodes[a]._parent)
+		cb, b = b, int(t.nodes[b]._parent)
+	}
+	/* a is common ancestor, and at most one of i and j is it */
+	if ca == null {
+		if t.dir(cb, a) == right {
+			return -1
 		}
-		i, node = pix, &t.nodes[pix]
+		return 1
 	}
-	panic("tree broken")
+	if t.dir(ca, a) == left {
+		return -1
+	}
+	return 1
 }
 
 // Insert adds in-order element of sort.Interface at index Tree.Len()
 // It doesn't check for equality, so duplicates are inserted in
 // stable order.
 func (t *Tree) Insert(data sort.Interface) {
-	ix := len(t.nodes)
-	if ix == MaxSize {
-		panic("tree size exceed maximum")
+	if err := t.TryInsert(data); err != nil {
+		panic(err)
+	}
+}
+
+// TryInsert is like Insert, but returns ErrCapacity
+// instead of exceeding MaxSize.
+func (t *Tree) TryInsert(data sort.Interface) error {
+	if len(t.nodes) == MaxSize {
+		return ErrCapacity
 	}
-	t.nodes = append(t.nodes, node{null, null, null, 1})
+	ix := t.push()
 	if ix == 0 {
-		t.root, t.min, t.max = 0, 0, 0
+		return nil
+	}
+	t.insert(data, ix)
+	return nil
+}
+
+// InsertBefore adds new element at specified position.
+// It trust you and doesn't check insertion position.
+func (t *Tree) InsertBefore(cur int) {
+	if err := t.TryInsertBefore(cur); err != nil {
+		panic(err)
+	}
+}
+
+// TryInsertBefore is like InsertBefore, but returns ErrOutOfRange
+// if cur is not an index of element or Len(),
+// and ErrCapacity instead of exceeding MaxSize.
+func (t *Tree) TryInsertBefore(cur int) error {
+	if cur < 0 || cur > len(t.nodes) {
+		return ErrOutOfRange
+	}
+	if len(t.nodes) == MaxSize {
+		return ErrCapacity
+	}
+	ix := t.push()
+	if ix == 0 {
+		return nil
+	}
+	if cur == ix {
+		cur = null
+	}
+	t.insertBefore(ix, cur)
+	return nil
+}
+
+// Fix restores order after key of element ix was changed
+// (like heap.Fix). Element keeps its index in data,
+// so no other element is moved.
+func (t *Tree) Fix(data sort.Interface, ix int) {
+	if ix < 0 || ix >= len(t.nodes) {
+		panic(ErrOutOfRange)
+	}
+	prev, next := t.Prev(ix), t.Next(ix)
+	if (prev == -1 || !data.Less(ix, prev)) &&
+		(next == len(t.nodes) || !data.Less(next, ix)) {
+		if t.aug != nil {
+			/* order is kept, but aggregates should be updated */
+			t.own()
+			for cur := ix; cur != null; cur = int(t.nodes[cur]._parent) {
+				t.fix(cur)
+			}
+		}
+		return
+	}
+	t.own()
+	t.detach(ix)
+	t.fix(ix)
+	t.insert(data, ix)
+}
+
+// MoveBefore moves element ix to position in front of element cur,
+// or after maximal element if cur is Len().
+// As InsertBefore, it trusts you and doesn't check order.
+// Element keeps its index in data, so no other element is moved.
+func (t *Tree) MoveBefore(ix, cur int) {
+	if ix < 0 || ix >= len(t.nodes) || cur < 0 || cur > len(t.nodes) {
+		panic(ErrOutOfRange)
+	}
+	if ix == cur {
 		return
 	}
+	t.own()
+	t.detach(ix)
+	t.fix(ix)
+	if t.root == null {
+		t.root, t.min, t.max = ix, ix, ix
+		return
+	}
+	if cur == len(t.nodes) {
+		cur = null
+	}
+	t.insertBefore(ix, cur)
+}
+
+// insert links detached node ix into non-empty tree
+// in sorted position after all equal elements
+func (t *Tree) insert(data sort.Interface, ix int) {
 	var dir direction
 	cur := t.root
 	curnode := &t.nodes[cur]
@@ -197,49 +457,48 @@ func (t *Tree) Insert(data sort.Interface) {
 		cur = curnode.link(dir)
 		curnode = &t.nodes[cur]
 	}
-	node := &t.nodes[ix]
-	node._parent = index(cur)
-	curnode.set_link(dir, ix)
-	if dir == right {
-		if cur == t.max {
-			t.max = ix
-		}
-	} else if cur == t.min {
-		t.min = ix
+	t.attach(cur, dir, ix)
+}
+
+// insertBefore links detached node ix into non-empty tree
+// in front of element cur, or after maximal element if cur is null
+func (t *Tree) insertBefore(ix, cur int) {
+	dir := left
+	if cur == null {
+		dir, cur = right, t.max
+	} else if t.nodes[cur]._left != null {
+		dir = right
+		cur = t.Prev(cur)
 	}
-	t.balance(cur)
+	t.attach(cur, dir, ix)
 }
 
-// InsertBefore adds new element at specified position.
-// It trust you and doesn't check insertion position.
-func (t *Tree) InsertBefore(cur int) {
+// push appends new detached node and returns its index.
+// First node becomes root of a tree.
+func (t *Tree) push() int {
 	ix := len(t.nodes)
 	if ix == MaxSize {
-		panic("tree size exceed maximum")
+		panic(ErrCapacity)
+	}
+	t.own()
+	t.nodes = append(t.nodes, node{null, null, null, 1, 1})
+	if t.h != nil {
+		t.h.add(ix)
+	}
+	if t.aug != nil {
+		t.aug(ix, null, null)
 	}
-	t.nodes = append(t.nodes, node{null, null, null, 1})
-	dir := left
 	if ix == 0 {
-		if cur != 0 {
-			panic("InsertBefore on empty tree accepts only 0")
-		}
 		t.root, t.min, t.max = 0, 0, 0
-		return
-	}
-	var curnode *node
-	if cur == ix {
-		dir, cur = right, t.max
-		curnode = &t.nodes[cur]
-	} else {
-		curnode = &t.nodes[cur]
-		if curnode._left != null {
-			dir = right
-			cur = t.Prev(cur)
-			curnode = &t.nodes[cur]
-		}
 	}
-	node := &t.nodes[ix]
-	node._parent = index(cur)
+	return ix
+}
+
+// attach links node ix as a child of cur in direction dir
+// and rebalances tree.
+func (t *Tree) attach(cur int, dir direction, ix int) {
+	curnode := &t.nodes[cur]
+	t.nodes[ix]._parent = index(cur)
 	curnode.set_link(dir, ix)
 	if dir == right {
 		if cur == t.max {
@@ -252,18 +511,36 @@ func (t *Tree) InsertBefore(cur int) {
 }
 
 // Delete removes element from a tree and return index of next in-order element
+// Deleted element is moved to index Len() of data, and other elements
+// could be moved to fill its place. If data implements Relocator,
+// it is notified about every such move.
 func (t *Tree) Delete(data sort.Interface, ix int) int {
+	return must(t.TryDelete(data, ix))
+}
+
+// TryDelete is like Delete, but returns ErrOutOfRange
+// if ix is not an index of element.
+func (t *Tree) TryDelete(data sort.Interface, ix int) (int, error) {
 	if ix < 0 || ix >= len(t.nodes) {
-		panic("Tree.Delete out of range")
+		return 0, ErrOutOfRange
 	}
-	if len(t.nodes) == 0 {
-		t.nodes = t.nodes[:0]
-		return 0
+	if rel, ok := data.(Relocator); ok {
+		rec := &recorder{Interface: data}
+		next, err := t.TryDelete(rec, ix)
+		if err != nil {
+			return 0, err
+		}
+		rec.report(rel, len(t.nodes))
+		return next, nil
 	}
+	t.own()
 	node := &t.nodes[ix]
-	next := t.Next(ix)
+	next, err := t.TryNext(ix)
+	if err != nil {
+		return 0, err
+	}
 	if node._left != null && node._right != null {
-		data.Swap(ix, next)
+		t.swap(data, ix, next)
 		next, ix, node = ix, next, &t.nodes[next]
 		/* at this moment order is temporary broken,
 		   but it will be restored after complete */
@@ -271,19 +548,35 @@ func (t *Tree) Delete(data sort.Interface, ix int) int {
 	return t.del(data, node, ix, next)
 }
 
-// DeleteAndPrev removes element from a tree and return index of next in-order element
+// DeleteAndPrev removes element from a tree and return index of previous in-order element
+// Elements are moved and reported to Relocator the same way as in Delete.
 func (t *Tree) DeleteAndPrev(data sort.Interface, ix int) int {
+	return must(t.TryDeleteAndPrev(data, ix))
+}
+
+// TryDeleteAndPrev is like DeleteAndPrev, but returns ErrOutOfRange
+// if ix is not an index of element.
+func (t *Tree) TryDeleteAndPrev(data sort.Interface, ix int) (int, error) {
 	if ix < 0 || ix >= len(t.nodes) {
-		panic("Tree.Delete out of range")
+		return 0, ErrOutOfRange
 	}
-	if len(t.nodes) == 0 {
-		t.nodes = t.nodes[:0]
-		return 0
+	if rel, ok := data.(Relocator); ok {
+		rec := &recorder{Interface: data}
+		prev, err := t.TryDeleteAndPrev(rec, ix)
+		if err != nil {
+			return 0, err
+		}
+		rec.report(rel, len(t.nodes))
+		return prev, nil
 	}
+	t.own()
 	node := &t.nodes[ix]
-	prev := t.Prev(ix)
+	prev, err := t.TryPrev(ix)
+	if err != nil {
+		return 0, err
+	}
 	if node._left != null && node._right != null {
-		data.Swap(ix, prev)
+		t.swap(data, ix, prev)
 		prev, ix, node = ix, prev, &t.nodes[prev]
 		/* at this moment order is temporary broken,
 		   but it will be restored after complete */
@@ -293,18 +586,27 @@ func (t *Tree) DeleteAndPrev(data sort.Interface, ix int) int {
 
 // LeaveSorted breaks link between Tree and sort.Interface
 // and leaves sort.Interface sorted.
+// If handles are enabled, they keep referencing elements
+// in sorted data until tree is modified.
 func (t *Tree) LeaveSorted(data sort.Interface) {
-	for i := t.Len(); i > 0; i-- {
+	n := t.Len()
+	for i := n; i > 0; i-- {
 		t.Delete(data, t.max)
 	}
+	if t.h != nil {
+		t.h.leave(n)
+	}
 }
 
 // Init fills tree structure accordantly to data in sort.Inteface
 func (t *Tree) Init(data sort.Interface) {
 	if data.Len() > MaxSize {
-		panic("tree size exceed maximum")
+		panic(ErrCapacity)
+	}
+	*t = Tree{h: t.h, aug: t.aug}
+	if t.h != nil {
+		t.h.reset(0)
 	}
-	*t = Tree{}
 	t.nodes = make([]node, 0, data.Len())
 	for i := data.Len(); i > 0; i-- {
 		t.Insert(data)
@@ -314,9 +616,12 @@ func (t *Tree) Init(data sort.Interface) {
 // InitSorted fills tree structure assuming data is sorted
 func (t *Tree) InitSorted(size int) {
 	if size > MaxSize {
-		panic("tree size exceed maximum")
+		panic(ErrCapacity)
+	}
+	*t = Tree{max: size - 1, h: t.h, aug: t.aug}
+	if t.h != nil {
+		t.h.reset(size)
 	}
-	*t = Tree{max: size - 1}
 	t.nodes = make([]node, size)
 	root, _ := t.initSorted(0, index(size), null)
 	t.root = int(root)
@@ -334,10 +639,81 @@ func (t *Tree) initSorted(a, b, p index) (m index, d int8) {
 	n._left, dl = t.initSorted(a, m, m)
 	n._right, dr = t.initSorted(m+1, b, m)
 	n.height = max_i8(dl, dr) + 1
+	n.size = b - a
+	if t.aug != nil {
+		t.aug(int(m), int(n._left), int(n._right))
+	}
 	return m, n.height
 }
 
-func (t *Tree) del(data sort.Interface, node *node, ix, next int) int {
+// detach removes node ix from tree structure without moving elements,
+// so that node ix is left unlinked.
+func (t *Tree) detach(ix int) {
+	n := &t.nodes[ix]
+	var start int
+	if n._left != null && n._right != null {
+		/* successor takes place of removed node */
+		s := int(n._right)
+		for t.nodes[s]._left != null {
+			s = int(t.nodes[s]._left)
+		}
+		snode := &t.nodes[s]
+		start = int(snode._parent)
+		if start == ix {
+			start = s
+		} else {
+			t.nodes[start]._left = snode._right
+			if snode._right != null {
+				t.nodes[snode._right]._parent = index(start)
+			}
+			snode._right = n._right
+			t.nodes[n._right]._parent = index(s)
+		}
+		snode._left = n._left
+		t.nodes[n._left]._parent = index(s)
+		t.replace(ix, s)
+	} else {
+		ch := int(n._left)
+		if ch == null {
+			ch = int(n._right)
+		}
+		start = int(n._parent)
+		t.replace(ix, ch)
+	}
+	*n = node{null, null, null, 1, 1}
+	t.balance(start)
+	if t.root == null {
+		t.min, t.max = null, null
+		return
+	}
+	if t.min == ix {
+		t.min = t.root
+		for t.nodes[t.min]._left != null {
+			t.min = int(t.nodes[t.min]._left)
+		}
+	}
+	if t.max == ix {
+		t.max = t.root
+		for t.nodes[t.max]._right != null {
+			t.max = int(t.nodes[t.max]._right)
+		}
+	}
+}
+
+// replace puts subtree ch (possibly empty) in place of node ix
+func (t *Tree) replace(ix, ch int) {
+	p := t.nodes[ix]._parent
+	if ch != null {
+		t.nodes[ch]._parent = p
+	}
+	if p == null {
+		t.root = ch
+	} else {
+		t.nodes[p].set_link(t.dir(ix, int(p)), ch)
+	}
+}
+
+func (t *Tree) del(data sort.Interface, node *node, ix, next int) (int, error) {
 	pix := int(node._parent)
 	if pix == null {
 		if node._left == null {
@@ -361,7 +737,10 @@ func (t *Tree) del(data sort.Interface, node *node, ix, next int) int {
 		}
 		pix = t.root
 	} else {
-		pdir := t.dir(ix, pix)
+		pdir, err := t.tryDir(ix, pix)
+		if err != nil {
+			return 0, err
+		}
 		parent := &t.nodes[pix]
 		if node._left == null {
 			rix := int(node._right)
@@ -394,10 +773,12 @@ func (t *Tree) del(data sort.Interface, node *node, ix, next int) int {
 	}
 	if ix != len(t.nodes)-1 {
 		jx := len(t.nodes) - 1
-		data.Swap(ix, jx)
+		t.swap(data, ix, jx)
 		inode, jnode := &t.nodes[ix], &t.nodes[jx]
 		*inode, *jnode = *jnode, *inode
-		t.fixlinks(inode, jx, ix)
+		if err := t.fixlinks(inode, jx, ix); err != nil {
+			return 0, err
+		}
 		if next == jx {
 			next = ix
 		}
@@ -405,12 +786,23 @@ func (t *Tree) del(data sort.Interface, node *node, ix, next int) int {
 			pix = ix
 		}
 	}
+	if t.h != nil {
+		t.h.remove()
+	}
 	t.nodes = t.nodes[:len(t.nodes)-1]
 	t.balance(pix)
-	return next
+	return next, nil
+}
+
+// swap exchanges elements of data together with their handles
+func (t *Tree) swap(data sort.Interface, i, j int) {
+	data.Swap(i, j)
+	if t.h != nil {
+		t.h.swap(i, j)
+	}
 }
 
-func (t *Tree) fixlinks(inode *node, i, j int) {
+func (t *Tree) fixlinks(inode *node, i, j int) error {
 	if inode._parent != null {
 		parent := &t.nodes[inode._parent]
 		dir := direction(int(parent._right) == i)
@@ -418,12 +810,12 @@ func (t *Tree) fixlinks(inode *node, i, j int) {
 	} else if t.root == i {
 		t.root = j
 	} else {
-		panic("tree broken")
+		return ErrCorrupt
 	}
 	if inode._left != null {
 		lnode := &t.nodes[inode._left]
 		if int(lnode._parent) != i {
-			panic("tree broken")
+			return ErrCorrupt
 		}
 		lnode._parent = index(j)
 	} else if t.min == i {
@@ -432,12 +824,13 @@ func (t *Tree) fixlinks(inode *node, i, j int) {
 	if inode._right != null {
 		rnode := &t.nodes[inode._right]
 		if int(rnode._parent) != i {
-			panic("tree broken")
+			return ErrCorrupt
 		}
 		rnode._parent = index(j)
 	} else if t.max == i {
 		t.max = j
 	}
+	return nil
 }
 
 func (t *Tree) balance(cur int) {
@@ -450,7 +843,7 @@ func (t *Tree) balance(cur int) {
 		} else if lh-1 > rh {
 			dir = left
 		} else {
-			node.height = max_i8(lh, rh) + 1
+			t.fix(cur)
 			cur = int(node._parent)
 			continue
 		}
@@ -474,7 +867,7 @@ func (t *Tree) rotate(ix int, dir direction) {
 	p := node._parent
 	ch := node.link(dir)
 	if ch == null {
-		panic("wrong rotation direction")
+		panic(ErrCorrupt)
 	}
 	chnode := &t.nodes[ch]
 	node.set_link(dir, chnode.link(!dir))
@@ -484,21 +877,27 @@ func (t *Tree) rotate(ix int, dir direction) {
 	chnode.set_link(!dir, ix)
 	node._parent = index(ch)
 	chnode._parent = index(p)
-	t.fixheight(node)
-	t.fixheight(chnode)
+	t.fix(ix)
+	t.fix(ch)
 	if p != null {
 		pnode := &t.nodes[p]
 		pdir := direction(int(pnode._right) == ix)
 		pnode.set_link(pdir, ch)
-		t.fixheight(pnode)
+		t.fix(int(p))
 	} else {
 		t.root = ch
 	}
 }
 
-func (t *Tree) fixheight(n *node) {
+// fix recalculates height, size and augmentation of node from its children
+func (t *Tree) fix(ix int) {
+	n := &t.nodes[ix]
 	lh, rh := t.height(n._left), t.height(n._right)
 	n.height = max_i8(lh, rh) + 1
+	n.size = t.size(n._left) + t.size(n._right) + 1
+	if t.aug != nil {
+		t.aug(ix, int(n._left), int(n._right))
+	}
 }
 
 func (t *Tree) height(ix index) int8 {
@@ -508,15 +907,38 @@ func (t *Tree) height(ix index) int8 {
 	return t.nodes[ix].height
 }
 
+func (t *Tree) size(ix index) index {
+	if ix == null {
+		return 0
+	}
+	return t.nodes[ix].size
+}
+
+func (t *Tree) depth(ix int) int {
+	d := 0
+	for t.nodes[ix]._parent != null {
+		ix = int(t.nodes[ix]._parent)
+		d++
+	}
+	return d
+}
+
 func (t *Tree) dir(i, ipar int) direction {
+	d, err := t.tryDir(i, ipar)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (t *Tree) tryDir(i, ipar int) (direction, error) {
 	parent := &t.nodes[ipar]
 	if int(parent._left) == i {
-		return left
+		return left, nil
 	} else if int(parent._right) == i {
-		return right
-	} else {
-		panic("tree broken")
+		return right, nil
 	}
+	return left, ErrCorrupt
 }
 
 func (t *Tree) bal(ix int) int8 {
@@ -1,10 +1,12 @@
 package tree
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"sort"
 	"testing"
+	"unsafe"
 )
 
 var _ = fmt.Println
@@ -12,6 +14,9 @@ var _ = fmt.Println
 func check(t *testing.T, data sort.Interface, tree *Tree, ix int) int8 {
 	node := &tree.nodes[ix]
 	l, r := int(node._left), int(node._right)
+	if node.size != tree.size(node._left)+tree.size(node._right)+1 {
+		t.Fatalf("size fails: %d", ix)
+	}
 	var lh, rh int8
 	if l != null {
 		if data.Less(ix, l) {
@@ -151,6 +156,49 @@ func Test_Delete(t *testing.T) {
 	test_delete(t, data, &tree, 1000)
 }
 
+type relslice struct {
+	sort.IntSlice
+	pos map[int]int
+}
+
+func (r relslice) Relocate(from, to int) {
+	if r.pos[r.IntSlice[to]] != from {
+		panic("wrong relocation source")
+	}
+	r.pos[r.IntSlice[to]] = to
+}
+
+func Test_Relocator(t *testing.T) {
+	for k := 0; k < 100; k++ {
+		data := relslice{sort.IntSlice{}, map[int]int{}}
+		tree := Tree{}
+		for i := 0; i < 100; i++ {
+			data.IntSlice = append(data.IntSlice, i)
+			data.pos[i] = i
+			tree.Insert(data)
+		}
+		for tree.Len() > 0 {
+			ix := rand.Intn(tree.Len())
+			v := data.IntSlice[ix]
+			if k&1 == 0 {
+				tree.Delete(data, ix)
+			} else {
+				tree.DeleteAndPrev(data, ix)
+			}
+			if data.IntSlice[tree.Len()] != v {
+				t.Fatalf("Delete don't place value at last position")
+			}
+			delete(data.pos, v)
+			data.IntSlice = data.IntSlice[:tree.Len()]
+			for i, v := range data.IntSlice {
+				if data.pos[v] != i {
+					t.Fatalf("position of %d is %d, reported %d", v, i, data.pos[v])
+				}
+			}
+		}
+	}
+}
+
 func Test_InitSorted(t *testing.T) {
 	data := sort.IntSlice{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}
 	tree := Tree{}
@@ -174,6 +222,404 @@ func Test_InitSorted(t *testing.T) {
 	check_iter(t, data, &tree)
 }
 
+func check_rank(t *testing.T, tree *Tree) {
+	if tree.Len() == 0 {
+		return
+	}
+	k := 0
+	for ix := tree.Next(-1); ix < tree.Len(); ix = tree.Next(ix) {
+		if r := tree.Rank(ix); r != k {
+			t.Fatalf("Rank(%d) = %d, want %d", ix, r, k)
+		}
+		if s := tree.Select(k); s != ix {
+			t.Fatalf("Select(%d) = %d, want %d", k, s, ix)
+		}
+		k++
+	}
+	if tree.Select(-1) != -1 || tree.Select(tree.Len()) != tree.Len() {
+		t.Fatalf("Select out of range")
+	}
+}
+
+func Test_RankSelect(t *testing.T) {
+	for k := 0; k < 100; k++ {
+		data := sort.IntSlice{}
+		tree := Tree{}
+		for i := 0; i < 100; i++ {
+			data = append(data, rand.Intn(50))
+			tree.Insert(data)
+		}
+		check_rank(t, &tree)
+		for tree.Len() > 0 {
+			tree.Delete(data, rand.Intn(tree.Len()))
+			data = data[:tree.Len()]
+			if tree.Len() > 0 {
+				check(t, data, &tree, tree.root)
+			}
+			check_rank(t, &tree)
+		}
+	}
+	tree := Tree{}
+	tree.InitSorted(77)
+	check_rank(t, &tree)
+}
+
+func Test_Compare(t *testing.T) {
+	tree := Tree{}
+	for i := 0; i < 300; i++ {
+		tree.InsertBefore(rand.Intn(tree.Len() + 1))
+	}
+	for k := 0; k < 3000; k++ {
+		i, j := rand.Intn(tree.Len()), rand.Intn(tree.Len())
+		want := 0
+		if ri, rj := tree.Rank(i), tree.Rank(j); ri < rj {
+			want = -1
+		} else if ri > rj {
+			want = 1
+		}
+		if c := tree.Compare(i, j); c != want {
+			t.Fatalf("Compare(%d, %d) = %d, want %d", i, j, c, want)
+		}
+	}
+}
+
+func Test_Find(t *testing.T) {
+	data := sort.IntSlice{}
+	tree := Tree{}
+	for i := 0; i < 300; i++ {
+		data = append(data, rand.Intn(100)*2)
+		tree.Insert(data)
+	}
+	for v := -3; v < 203; v++ {
+		cmp := func(i int) int { return data[i] - v }
+		first, last := tree.EqualRange(cmp)
+		cnt := 0
+		for _, x := range data {
+			if x == v {
+				cnt++
+			}
+		}
+		if cnt == 0 {
+			if tree.Find(cmp) != tree.Len() || first != tree.Len() || last != -1 {
+				t.Fatalf("found missing %d", v)
+			}
+		} else {
+			if ix := tree.Find(cmp); ix != first || data[ix] != v {
+				t.Fatalf("Find(%d) = %d", v, ix)
+			}
+			if data[last] != v || tree.Rank(last)-tree.Rank(first)+1 != cnt {
+				t.Fatalf("EqualRange(%d) = %d, %d", v, first, last)
+			}
+		}
+		check := func(name string, ix, bad int, ok func(x int) bool, next func(i int) int) {
+			if ix == bad {
+				for _, x := range data {
+					if ok(x) {
+						t.Fatalf("%s(%d) found nothing, but %d matches", name, v, x)
+					}
+				}
+				return
+			}
+			if !ok(data[ix]) {
+				t.Fatalf("%s(%d) = %d", name, v, data[ix])
+			}
+			if n := next(ix); n >= 0 && n < tree.Len() && ok(data[n]) && data[n] != data[ix] {
+				t.Fatalf("%s(%d) = %d is not the closest", name, v, data[ix])
+			}
+		}
+		check("Floor", tree.Floor(cmp), -1, func(x int) bool { return x <= v }, tree.Next)
+		check("Lower", tree.Lower(cmp), -1, func(x int) bool { return x < v }, tree.Next)
+		check("Ceiling", tree.Ceiling(cmp), tree.Len(), func(x int) bool { return x >= v }, tree.Prev)
+		check("Higher", tree.Higher(cmp), tree.Len(), func(x int) bool { return x > v }, tree.Prev)
+	}
+}
+
+func Test_Fix(t *testing.T) {
+	data := sort.IntSlice{}
+	tree := Tree{}
+	for i := 0; i < 200; i++ {
+		data = append(data, rand.Intn(100))
+		tree.Insert(data)
+	}
+	for i := 0; i < 1000; i++ {
+		ix := rand.Intn(tree.Len())
+		data[ix] = rand.Intn(100)
+		tree.Fix(data, ix)
+		check_tree(t, data, &tree)
+	}
+	one := Tree{}
+	one.Insert(data[:1])
+	one.Fix(data[:1], 0)
+	check_tree(t, data[:1], &one)
+}
+
+func Test_MoveBefore(t *testing.T) {
+	tree := Tree{}
+	order := []int{}
+	for i := 0; i < 100; i++ {
+		pos := rand.Intn(len(order) + 1)
+		if pos == len(order) {
+			tree.InsertBefore(tree.Len())
+		} else {
+			tree.InsertBefore(order[pos])
+		}
+		order = append(order[:pos], append([]int{i}, order[pos:]...)...)
+	}
+	for i := 0; i < 1000; i++ {
+		ix := rand.Intn(tree.Len())
+		cur := rand.Intn(tree.Len() + 1)
+		tree.MoveBefore(ix, cur)
+		if ix != cur {
+			for k, v := range order {
+				if v == ix {
+					order = append(order[:k], order[k+1:]...)
+					break
+				}
+			}
+			pos := len(order)
+			for k, v := range order {
+				if v == cur {
+					pos = k
+				}
+			}
+			order = append(order[:pos], append([]int{ix}, order[pos:]...)...)
+		}
+		k := 0
+		for ix := range tree.All() {
+			if ix != order[k] {
+				t.Fatalf("MoveBefore: %d at position %d, want %d", ix, k, order[k])
+			}
+			k++
+		}
+		check(t, sort.IntSlice(make([]int, tree.Len())), &tree, tree.root)
+	}
+}
+
+func Test_InsertBatch(t *testing.T) {
+	for k := 0; k < 200; k++ {
+		data := tslice{}
+		tree := Tree{}
+		if k%3 == 0 {
+			tree.EnableHandles()
+		}
+		for b := 0; b < 5; b++ {
+			n := rand.Intn(100)
+			if k%2 == 0 {
+				n = rand.Intn(5)
+			}
+			for i := 0; i < n; i++ {
+				data = append(data, tstruct{rand.Intn(30), len(data)})
+			}
+			tree.InsertBatch(data, n)
+			if err := tree.Verify(data); err != nil {
+				t.Fatal(err)
+			}
+			for i, v := range data {
+				if v.Ix != i {
+					t.Fatalf("InsertBatch moved element %d to %d", v.Ix, i)
+				}
+			}
+		}
+		tree.LeaveSorted(data)
+		data.CheckSorted(t)
+	}
+}
+
+func Test_InsertBatchRelocator(t *testing.T) {
+	data := relslice{sort.IntSlice{}, map[int]int{}}
+	tree := Tree{}
+	for b := 0; b < 5; b++ {
+		n := 10 + rand.Intn(20)
+		for i := 0; i < n; i++ {
+			v := len(data.IntSlice)*1000 + rand.Intn(1000)
+			data.IntSlice = append(data.IntSlice, v%1000*1000+v/1000)
+			data.pos[data.IntSlice[len(data.IntSlice)-1]] = len(data.IntSlice) - 1
+		}
+		tree.InsertBatch(data, n)
+		if err := tree.Verify(data); err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range data.IntSlice {
+			if data.pos[v] != i {
+				t.Fatalf("position of %d is %d, reported %d", v, i, data.pos[v])
+			}
+		}
+	}
+}
+
+func Test_DeleteFunc(t *testing.T) {
+	for k := 0; k < 200; k++ {
+		data := relslice{sort.IntSlice{}, map[int]int{}}
+		tree := Tree{}
+		if k%3 == 0 {
+			tree.EnableHandles()
+		}
+		n := rand.Intn(100)
+		for i := 0; i < n; i++ {
+			data.IntSlice = append(data.IntSlice, rand.Intn(1000)*1000+i)
+			data.pos[data.IntSlice[i]] = i
+			tree.Insert(data)
+		}
+		hs := map[Handle]int{}
+		if tree.h != nil {
+			for i, v := range data.IntSlice {
+				hs[tree.Handle(i)] = v
+			}
+		}
+		var want []int
+		var removed int
+		if k%2 == 0 {
+			for _, v := range data.IntSlice {
+				if v%3 != 0 {
+					want = append(want, v)
+				}
+			}
+			removed = tree.DeleteFunc(data, func(i int) bool {
+				return data.IntSlice[i]%3 == 0
+			})
+		} else {
+			lo, hi := rand.Intn(1000000), rand.Intn(1000000)
+			for _, v := range data.IntSlice {
+				if v < lo || v > hi {
+					want = append(want, v)
+				}
+			}
+			removed = tree.DeleteRange(data,
+				func(i int) bool { return data.IntSlice[i] >= lo },
+				func(i int) bool { return data.IntSlice[i] <= hi })
+		}
+		if removed != n-len(want) || tree.Len() != len(want) {
+			t.Fatalf("removed %d of %d, want %d", removed, n, n-len(want))
+		}
+		for _, v := range data.IntSlice[tree.Len():] {
+			delete(data.pos, v)
+		}
+		data.IntSlice = data.IntSlice[:tree.Len()]
+		if err := tree.Verify(data); err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range data.IntSlice {
+			if data.pos[v] != i {
+				t.Fatalf("position of %d is %d, reported %d", v, i, data.pos[v])
+			}
+		}
+		for h, v := range hs {
+			ix := tree.Index(h)
+			_, live := data.pos[v]
+			if ix >= 0 && data.IntSlice[ix] != v || ix < 0 && live {
+				t.Fatalf("handle %d of %d points to %d", h, v, ix)
+			}
+		}
+		sort.Ints(want)
+		i := 0
+		for ix := range tree.All() {
+			if data.IntSlice[ix] != want[i] {
+				t.Fatalf("element %d is %d, want %d", i, data.IntSlice[ix], want[i])
+			}
+			i++
+		}
+	}
+}
+
+func Test_MaxSize(t *testing.T) {
+	if int(index(MaxSize)) != MaxSize || index(MaxSize)*2+1 < 0 {
+		t.Fatalf("MaxSize %d doesn't fit index", MaxSize)
+	}
+	width := unsafe.Sizeof(index(0))
+	if unsafe.Sizeof(node{}) != 5*width {
+		t.Fatalf("node takes %d bytes, want %d", unsafe.Sizeof(node{}), 5*width)
+	}
+}
+
+func Test_Errors(t *testing.T) {
+	tree := Tree{}
+	data := sort.IntSlice{}
+	if _, err := tree.TryMin(); err != ErrEmpty {
+		t.Fatalf("TryMin on empty tree: %v", err)
+	}
+	if _, err := tree.TryMax(); err != ErrEmpty {
+		t.Fatalf("TryMax on empty tree: %v", err)
+	}
+	if err := tree.TryInsertBefore(1); err != ErrOutOfRange {
+		t.Fatalf("TryInsertBefore on empty tree: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		data = append(data, i)
+		if err := tree.TryInsert(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tree.TryDelete(data, 10); err != ErrOutOfRange {
+		t.Fatalf("TryDelete out of range: %v", err)
+	}
+	if _, err := tree.TryDeleteAndPrev(data, -1); err != ErrOutOfRange {
+		t.Fatalf("TryDeleteAndPrev out of range: %v", err)
+	}
+	if _, err := tree.TryNext(11); err != ErrOutOfRange {
+		t.Fatalf("TryNext overflow: %v", err)
+	}
+	if _, err := tree.TryPrev(-2); err != ErrOutOfRange {
+		t.Fatalf("TryPrev overflow: %v", err)
+	}
+	if ix, err := tree.TryNext(tree.Min()); err != nil || data[ix] != 1 {
+		t.Fatalf("TryNext: %d %v", ix, err)
+	}
+	if ix, err := tree.TryPrev(tree.Max()); err != nil || data[ix] != 8 {
+		t.Fatalf("TryPrev: %d %v", ix, err)
+	}
+	leaf := tree.Min()
+	tree.nodes[tree.nodes[leaf]._parent]._left = null
+	if _, err := tree.TryNext(leaf); err != ErrCorrupt {
+		t.Fatalf("TryNext on broken tree: %v", err)
+	}
+	if _, err := tree.TryDelete(data, leaf); err != ErrCorrupt {
+		t.Fatalf("TryDelete on broken tree: %v", err)
+	}
+	if _, err := tree.TryDeleteAndPrev(data, leaf); err != ErrCorrupt {
+		t.Fatalf("TryDeleteAndPrev on broken tree: %v", err)
+	}
+	if err := tree.Verify(data); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Verify on broken tree: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != ErrEmpty {
+			t.Fatalf("Min on empty tree panics with %v", r)
+		}
+	}()
+	(&Tree{}).Min()
+}
+
+func Test_PanicErrors(t *testing.T) {
+	panics := func(err error, fn func()) {
+		t.Helper()
+		defer func() {
+			r, _ := recover().(error)
+			if !errors.Is(r, err) {
+				t.Fatalf("panics with %v, want %v", r, err)
+			}
+		}()
+		fn()
+	}
+	data := sort.IntSlice{1}
+	tree := Tree{}
+	tree.Insert(data)
+	panics(ErrNoHandles, func() { tree.Handle(0) })
+	tree.EnableHandles()
+	panics(ErrOutOfRange, func() { tree.Handle(1) })
+	m := NewMultiIndex(2)
+	func() {
+		defer func() {
+			if r, ok := recover().(error); !ok || errors.Is(r, ErrOutOfRange) {
+				t.Fatalf("Insert with wrong orderings panics with %v", r)
+			}
+		}()
+		m.Insert(data)
+	}()
+	panics(ErrOutOfRange, func() { m.Delete(data, 0) })
+	s := NewSyncTree(func(a, b int) int { return a - b })
+	panics(ErrOutOfRange, func() { s.InsertBefore(1, 0) })
+}
+
 type tstruct struct {
 	I  int
 	Ix int
@@ -256,6 +702,41 @@ func benchmark_TreeInsert(b *testing.B, n int) {
 	}
 }
 
+func benchmark_TreeInsertBatch(b *testing.B, n int) {
+	for i := 0; i < b.N; i++ {
+		data := benchslice{}
+		tree := Tree{}
+		for j := 0; j < n; j++ {
+			data = append(data, bigstruct{I: rand.Intn(1 << 30)})
+		}
+		tree.InsertBatch(data, n)
+	}
+}
+
+func benchmark_TreeAppend(b *testing.B, n int, batch bool) {
+	data := benchslice{}
+	tree := Tree{}
+	random_tree(&data, &tree, n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		d := append(benchslice{}, data...)
+		t := tree
+		t.nodes = append([]node(nil), tree.nodes...)
+		for j := 0; j < n/10; j++ {
+			d = append(d, bigstruct{I: rand.Intn(1 << 30)})
+		}
+		b.StartTimer()
+		if batch {
+			t.InsertBatch(d, n/10)
+		} else {
+			for j := 0; j < n/10; j++ {
+				t.Insert(d)
+			}
+		}
+	}
+}
+
 func benchmark_TreeSearch(b *testing.B, n int) {
 	data := benchslice{}
 	tree := Tree{}
@@ -390,3 +871,15 @@ func Benchmark_SortStable100(b *testing.B)   { benchmark_SortStable(b, 100) }
 func Benchmark_SortStable1000(b *testing.B)  { benchmark_SortStable(b, 1000) }
 func Benchmark_SortStable10000(b *testing.B) { benchmark_SortStable(b, 10000) }
 func Benchmark_SortStable30000(b *testing.B) { benchmark_SortStable(b, 30000) }
+
+func Benchmark_TreeInsertBatch10(b *testing.B)     { benchmark_TreeInsertBatch(b, 10) }
+func Benchmark_TreeInsertBatch100(b *testing.B)    { benchmark_TreeInsertBatch(b, 100) }
+func Benchmark_TreeInsertBatch1000(b *testing.B)   { benchmark_TreeInsertBatch(b, 1000) }
+func Benchmark_TreeInsertBatch10000(b *testing.B)  { benchmark_TreeInsertBatch(b, 10000) }
+func Benchmark_TreeInsertBatch30000(b *testing.B)  { benchmark_TreeInsertBatch(b, 30000) }
+func Benchmark_TreeAppend1000(b *testing.B)        { benchmark_TreeAppend(b, 1000, false) }
+func Benchmark_TreeAppend10000(b *testing.B)       { benchmark_TreeAppend(b, 10000, false) }
+func Benchmark_TreeAppend100000(b *testing.B)      { benchmark_TreeAppend(b, 100000, false) }
+func Benchmark_TreeAppendBatch1000(b *testing.B)   { benchmark_TreeAppend(b, 1000, true) }
+func Benchmark_TreeAppendBatch10000(b *testing.B)  { benchmark_TreeAppend(b, 10000, true) }
+func Benchmark_TreeAppendBatch100000(b *testing.B) { benchmark_TreeAppend(b, 100000, true) }
@@ -0,0 +1,50 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Verify checks consistency of tree and its data: parent and child
+// links, AVL heights, subtree sizes, root, min and max bookkeeping,
+// handles (if enabled) and in-order sortedness of data.
+// Returned error identifies offending node.
+func (t *Tree) Verify(data sort.Interface) error {
+	if data.Len() < len(t.nodes) {
+		return fmt.Errorf("%w: data has %d elements, but tree indexes %d", ErrCorrupt,
+			data.Len(), len(t.nodes))
+	}
+	if err := t.validate(false); err != nil {
+		return err
+	}
+	if t.h != nil {
+		if err := t.h.verify(len(t.nodes)); err != nil {
+			return err
+		}
+	}
+	if len(t.nodes) == 0 {
+		return nil
+	}
+	prev := t.min
+	for ix := t.Next(prev); ix < len(t.nodes); prev, ix = ix, t.Next(ix) {
+		if data.Less(ix, prev) {
+			return fmt.Errorf("%w: node %d: element is less than previous element %d", ErrCorrupt, ix, prev)
+		}
+	}
+	return nil
+}
+
+func (h *handles) verify(n int) error {
+	if len(h.of) != n {
+		return fmt.Errorf("%w: %d handles for %d nodes", ErrCorrupt, len(h.of), n)
+	}
+	if len(h.gen) != len(h.pos) {
+		return fmt.Errorf("%w: %d generations for %d handle slots", ErrCorrupt, len(h.gen), len(h.pos))
+	}
+	for ix, hd := range h.of {
+		if hd < 0 || int(hd) >= len(h.pos) || int(h.pos[hd]) != ix {
+			return fmt.Errorf("%w: node %d: handle %d is broken", ErrCorrupt, ix, hd)
+		}
+	}
+	return nil
+}
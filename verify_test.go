@@ -0,0 +1,53 @@
+package tree
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_Verify(t *testing.T) {
+	data := sort.IntSlice{}
+	tree := Tree{}
+	if err := tree.Verify(data); err != nil {
+		t.Fatalf("empty tree: %v", err)
+	}
+	tree.EnableHandles()
+	for i := 0; i < 100; i++ {
+		data = append(data, rand.Intn(100))
+		tree.Insert(data)
+		if err := tree.Verify(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expect := func(msg string, corrupt func(tr *Tree, d sort.IntSlice)) {
+		tr := tree
+		tr.nodes = append([]node{}, tree.nodes...)
+		d := append(sort.IntSlice{}, data...)
+		corrupt(&tr, d)
+		err := tr.Verify(d)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("want error with %q, got %v", msg, err)
+		}
+	}
+	expect("less than previous", func(tr *Tree, d sort.IntSlice) {
+		d[tr.max] = -1
+	})
+	expect("height", func(tr *Tree, d sort.IntSlice) {
+		tr.nodes[tr.min].height = 3
+	})
+	expect("size", func(tr *Tree, d sort.IntSlice) {
+		tr.nodes[tr.root].size++
+	})
+	expect("has parent", func(tr *Tree, d sort.IntSlice) {
+		tr.nodes[tr.nodes[tr.root]._left]._parent = null
+	})
+	expect("max is", func(tr *Tree, d sort.IntSlice) {
+		tr.max = tr.min
+	})
+	if err := tree.Verify(data[:10]); err == nil {
+		t.Fatalf("short data is accepted")
+	}
+}